@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TypicalAM/goread/internal/backend"
+	"github.com/TypicalAM/goread/internal/backend/cache"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache [stats|prune|clear|verify]",
+	Short: "Inspect and repair the cache",
+	Long: `Inspect and repair the cache:
+  stats   show the cached feeds with their article counts, sizes and expiry times
+  prune   drop the feeds which are no longer in the urls file
+  clear   clear the cache, the saved articles and the read status are kept
+  verify  check that the cache and the read status files aren't corrupted`,
+	ValidArgs: []string{"stats", "prune", "clear", "verify"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(_ *cobra.Command, args []string) {
+		if err := RunCache(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, errStyle.Render(fmt.Sprint("Encountered an error: ", err)))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// RunCache runs a cache maintenance action
+func RunCache(action string) error {
+	defer logToFile()()
+
+	if action == "verify" {
+		return verifyCache()
+	}
+
+	// NOTE: Loading a corrupted cache yields an empty one, saving it would throw away the saved articles
+	if action != "stats" {
+		if err := verifyCache(); err != nil {
+			return err
+		}
+	}
+
+	backend, err := backend.New(opts.urlsPath, opts.cacheDir, false, nil)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "stats":
+		printCacheStats(backend)
+		return nil
+
+	case "prune":
+		var urls []string
+		for _, feed := range backend.Rss.GetAllFeeds() {
+			urls = append(urls, feed.URL)
+		}
+
+		if backend.ReadOnly {
+			return errors.New("another goread instance is running, close it before pruning the cache")
+		}
+
+		removed := backend.Cache.Prune(urls)
+		if err = backend.Cache.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println(msgStyle.Render(fmt.Sprintf("Pruned %d cache entries", removed)))
+
+	case "clear":
+		if backend.ReadOnly {
+			return errors.New("another goread instance is running, close it before clearing the cache")
+		}
+
+		backend.Cache.Clear()
+		if err = backend.Cache.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println(msgStyle.Render("Cleared the cache, the saved articles and the read status were kept"))
+	}
+
+	return nil
+}
+
+// verifyCache checks the cache and the read status files for corruption
+func verifyCache() error {
+	store, err := cache.New(opts.cacheDir)
+	if err != nil {
+		return err
+	}
+
+	readStatus, err := cache.NewReadStatus(opts.cacheDir)
+	if err != nil {
+		return err
+	}
+
+	cacheErr, readStatusErr := store.Verify(), readStatus.Verify()
+	if cacheErr != nil {
+		fmt.Fprintln(os.Stderr, errStyle.Render(fmt.Sprint("Cache: ", cacheErr)))
+	}
+
+	if readStatusErr != nil {
+		fmt.Fprintln(os.Stderr, errStyle.Render(fmt.Sprint("Read status: ", readStatusErr)))
+	}
+
+	if cacheErr != nil || readStatusErr != nil {
+		return errors.New("the cache didn't pass verification")
+	}
+
+	fmt.Println(msgStyle.Render("The cache and the read status are fine"))
+	return nil
+}
+
+// printCacheStats prints the cached feeds in a table, the feeds which are no longer in the urls file are marked
+func printCacheStats(b *backend.Backend) {
+	names := make(map[string]string)
+	for _, feed := range b.Rss.GetAllFeeds() {
+		names[feed.URL] = feed.Name
+	}
+
+	now := time.Now()
+	entries := b.Cache.Stats()
+	totalArticles, totalSize := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FEED\tARTICLES\tSIZE\tEXPIRES\tLAST USED\tURL")
+	for _, stats := range entries {
+		name, ok := names[stats.URL]
+		if !ok {
+			name = "(not in the urls file)"
+		}
+
+		if stats.Health.Failing() {
+			name += " ⚠"
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", name, stats.Articles, formatSize(stats.Size),
+			formatRelative(stats.Expire, now), formatRelative(stats.LastUsed, now), stats.URL)
+		totalArticles += stats.Articles
+		totalSize += stats.Size
+	}
+
+	w.Flush()
+	fmt.Printf("\n%d feeds, %d articles, %s\n", len(entries), totalArticles, formatSize(totalSize))
+	fmt.Printf("%d saved articles, %d extracted articles\n", len(b.Cache.GetDownloaded()), len(b.Cache.Extracted))
+}
+
+// formatSize formats an amount of bytes in a human readable way
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+
+	return fmt.Sprintf("%d B", size)
+}
+
+// formatRelative formats a time relative to now, the times further than two days away are shown as dates
+func formatRelative(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	diff := t.Sub(now)
+	if diff < time.Minute && diff > -time.Minute {
+		return "now"
+	}
+
+	if diff > 48*time.Hour || diff < -48*time.Hour {
+		return t.Local().Format("2006-01-02 15:04")
+	}
+
+	if diff > 0 {
+		return "in " + strings.TrimSuffix(diff.Round(time.Minute).String(), "0s")
+	}
+
+	return strings.TrimSuffix((-diff).Round(time.Minute).String(), "0s") + " ago"
+}
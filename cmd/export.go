@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TypicalAM/goread/internal/backend"
+	"github.com/TypicalAM/goread/internal/backend/export"
+)
+
+// exportOptions denote the flags that can be given to the export command
+type exportOptions struct {
+	format string
+	output string
+	since  string
+	until  string
+	feeds  []string
+	tags   []string
+}
+
+var (
+	exportOpts = exportOptions{}
+	exportCmd  = &cobra.Command{
+		Use:       "export [saved]",
+		Short:     "Export the saved articles to Markdown, HTML or EPUB",
+		ValidArgs: []string{"saved"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Run: func(_ *cobra.Command, _ []string) {
+			if err := RunExport(); err != nil {
+				fmt.Fprintln(os.Stderr, errStyle.Render(fmt.Sprint("Encountered an error: ", err)))
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	exportCmd.Flags().
+		StringVarP(&exportOpts.format, "format", "f", "markdown", "The export format, one of markdown, html or epub")
+	exportCmd.Flags().
+		StringVarP(&exportOpts.output, "output", "o", "", "The output path, a directory for markdown and a file otherwise")
+	exportCmd.Flags().
+		StringVarP(&exportOpts.since, "since", "", "", "Only export the articles published on or after this date (YYYY-MM-DD)")
+	exportCmd.Flags().
+		StringVarP(&exportOpts.until, "until", "", "", "Only export the articles published on or before this date (YYYY-MM-DD)")
+	exportCmd.Flags().
+		StringArrayVarP(&exportOpts.feeds, "feed", "", nil, "Only export the articles from this feed, given by its name or url, can be repeated")
+	exportCmd.Flags().
+		StringArrayVarP(&exportOpts.tags, "tag", "", nil, "Only export the articles with this tag, can be repeated")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// RunExport exports the saved articles
+func RunExport() error {
+	defer logToFile()()
+
+	format, err := export.ParseFormat(exportOpts.format)
+	if err != nil {
+		return err
+	}
+
+	filter := export.Filter{Feeds: exportOpts.feeds, Tags: exportOpts.tags}
+	if exportOpts.since != "" {
+		if filter.Since, err = parseDate(exportOpts.since); err != nil {
+			return err
+		}
+	}
+
+	if exportOpts.until != "" {
+		until, err := parseDate(exportOpts.until)
+		if err != nil {
+			return err
+		}
+
+		// The whole day is included
+		filter.Until = until.AddDate(0, 0, 1)
+	}
+
+	path := exportOpts.output
+	if path == "" {
+		path = format.DefaultPath()
+	}
+
+	backend, err := backend.New(opts.urlsPath, opts.cacheDir, false, nil)
+	if err != nil {
+		return err
+	}
+
+	count, err := backend.ExportSaved(path, format, filter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(msgStyle.Render(fmt.Sprintf("Exported %d saved articles to %s", count, path)))
+	return nil
+}
+
+// parseDate parses a date given on the command line, in the local time zone
+func parseDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cmd.parseDate: %w", err)
+	}
+
+	return date, nil
+}
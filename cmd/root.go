@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,7 +18,9 @@ import (
 
 	"github.com/TypicalAM/goread/internal/backend"
 	"github.com/TypicalAM/goread/internal/backend/cache"
+	"github.com/TypicalAM/goread/internal/backend/rss"
 	"github.com/TypicalAM/goread/internal/config"
+	"github.com/TypicalAM/goread/internal/migrate"
 	"github.com/TypicalAM/goread/internal/theme"
 	"github.com/TypicalAM/goread/internal/ui/browser"
 )
@@ -29,11 +35,21 @@ type options struct {
 	loadOPMLFrom    string
 	exportOPMLTo    string
 	cacheSize       int
-	cacheDuration   int
+	cacheDuration   string
+	autoRefresh     string
+	historyAge      string
+	historySize     int
+	readStatusAge   string
+	fetchWorkers    int
+	fetchPerHost    int
+	fetchRetries    int
 	dumpColors      bool
 	testColors      bool
 	resetCache      bool
+	savePages       bool
 	urlsReadOnly    bool
+	dryRun          bool
+	allowCommands   bool
 }
 
 var (
@@ -53,7 +69,7 @@ var (
 )
 
 func init() {
-	rootCmd.Flags().
+	rootCmd.PersistentFlags().
 		StringVarP(&opts.cacheDir, "cache_dir", "", "", "The path to the cache directory")
 	rootCmd.PersistentFlags().
 		StringVarP(&opts.colorschemePath, "colorscheme_path", "c", "", "The path to the colorscheme file")
@@ -65,13 +81,33 @@ func init() {
 	rootCmd.Flags().
 		StringVarP(&opts.getColors, "get_colors", "", "", "Get the colors from pywal and save them to the colorscheme file")
 	rootCmd.Flags().BoolVarP(&opts.resetCache, "reset_cache", "", false, "Reset the cache")
-	rootCmd.Flags().IntVarP(&opts.cacheSize, "cache_size", "", 0, "The size of the cache")
 	rootCmd.Flags().
-		IntVarP(&opts.cacheDuration, "cache_duration", "", 0, "The duration of the cache in hours")
+		BoolVarP(&opts.savePages, "save_pages", "", false, "Save the full article page along with the images when saving an article")
+	rootCmd.Flags().IntVarP(&opts.cacheSize, "cache_size", "", 0, "The maximum amount of articles kept in the cache")
+	rootCmd.Flags().
+		StringVarP(&opts.cacheDuration, "cache_duration", "", "", "The duration of the cache, in hours or as a duration like 90m")
+	rootCmd.Flags().
+		StringVarP(&opts.autoRefresh, "auto_refresh", "", "", "How often the expired feeds are refreshed in the background, 0 disables it")
+	rootCmd.Flags().
+		StringVarP(&opts.historyAge, "history_age", "", "", "How long articles which dropped off their feed are kept, in days or as a duration like 48h, 0 keeps them forever")
+	rootCmd.Flags().
+		IntVarP(&opts.historySize, "history_size", "", -1, "The maximum amount of articles kept per feed, 0 means no limit")
+	rootCmd.Flags().
+		StringVarP(&opts.readStatusAge, "read_status_age", "", "", "How long articles are remembered as read, in days or as a duration like 48h, 0 remembers them forever")
+	rootCmd.Flags().
+		IntVarP(&opts.fetchWorkers, "fetch_workers", "", 0, "The amount of feeds fetched at the same time")
+	rootCmd.Flags().
+		IntVarP(&opts.fetchPerHost, "fetch_workers_per_host", "", 0, "The amount of feeds fetched at the same time from a single host")
+	rootCmd.Flags().
+		IntVarP(&opts.fetchRetries, "fetch_retries", "", -1, "The amount of times a failed feed download is retried")
 	rootCmd.Flags().
 		StringVarP(&opts.loadOPMLFrom, "load_opml", "i", "", "Import the feeds from an OPML file")
 	rootCmd.Flags().
 		StringVarP(&opts.exportOPMLTo, "export_opml", "e", "", "Export the feeds to an OPML file")
+	rootCmd.Flags().
+		BoolVarP(&opts.dryRun, "dry_run", "", false, "Show what importing an OPML file would change without changing anything")
+	rootCmd.Flags().
+		BoolVarP(&opts.allowCommands, "allow_commands", "", false, "Import the feeds of an OPML file which run commands (exec: and filter: urls), only use it with trusted files")
 	rootCmd.Flags().
 		BoolVarP(&opts.urlsReadOnly, "urls_readonly", "", false, "Feed urls config is read-only, skip saving the feed urls configuration")
 }
@@ -87,13 +123,7 @@ func SetVersion(version string) {
 
 // Run runs the program
 func Run() error {
-	if f, err := tea.LogToFile(filepath.Join(os.TempDir(), "goread.log"), ""); err == nil {
-		defer f.Close()
-	} else {
-		log.Println("Failed to create log file")
-		log.SetOutput(io.Discard)
-	}
-
+	defer logToFile()()
 	log.Println("Starting goread")
 
 	colors, err := theme.New(opts.colorschemePath)
@@ -103,6 +133,9 @@ func Run() error {
 
 	if err = colors.Load(); err != nil {
 		log.Println("Failed to load colorscheme: ", err)
+		if errors.Is(err, migrate.ErrTooNew) || errors.Is(err, migrate.ErrBackupFailed) {
+			return err
+		}
 	}
 
 	// Pretty printing colors
@@ -148,9 +181,73 @@ func Run() error {
 	}
 
 	// Set the cache duration
-	if opts.cacheDuration > 0 {
-		log.Println("Setting cache duration to ", opts.cacheDuration)
-		cache.DefaultCacheDuration = time.Hour * time.Duration(opts.cacheDuration)
+	if opts.cacheDuration != "" {
+		duration, err := parseCacheDuration(opts.cacheDuration)
+		if err != nil {
+			return err
+		}
+
+		log.Println("Setting cache duration to ", duration)
+		cache.DefaultCacheDuration = duration
+	}
+
+	// Set the background refresh interval
+	if opts.autoRefresh != "" {
+		interval, err := parseAutoRefresh(opts.autoRefresh)
+		if err != nil {
+			return err
+		}
+
+		log.Println("Setting the auto refresh interval to ", interval)
+		browser.DefaultAutoRefresh = interval
+	}
+
+	// Set the article history retention
+	if opts.historyAge != "" {
+		age, err := parseAge(opts.historyAge)
+		if err != nil {
+			return err
+		}
+
+		log.Println("Setting the history age to ", age)
+		cache.DefaultHistoryAge = age
+	}
+
+	if opts.historySize >= 0 {
+		log.Println("Setting the history size to ", opts.historySize)
+		cache.DefaultHistorySize = opts.historySize
+	}
+
+	// Set how long the read status is kept
+	if opts.readStatusAge != "" {
+		age, err := parseAge(opts.readStatusAge)
+		if err != nil {
+			return err
+		}
+
+		log.Println("Setting the read status age to ", age)
+		cache.DefaultReadStatusAge = age
+	}
+
+	if opts.savePages {
+		log.Println("Saving the article pages along with the saved articles")
+		cache.DefaultSavePages = true
+	}
+
+	// Set the amount of fetch workers
+	if opts.fetchWorkers > 0 {
+		log.Println("Setting fetch workers to ", opts.fetchWorkers)
+		cache.DefaultFetchWorkers = opts.fetchWorkers
+	}
+
+	if opts.fetchPerHost > 0 {
+		log.Println("Setting fetch workers per host to ", opts.fetchPerHost)
+		cache.DefaultFetchWorkersPerHost = opts.fetchPerHost
+	}
+
+	if opts.fetchRetries >= 0 {
+		log.Println("Setting fetch retries to ", opts.fetchRetries)
+		cache.DefaultFetchRetries = opts.fetchRetries
 	}
 
 	// Get the config
@@ -166,22 +263,33 @@ func Run() error {
 	}
 
 	// Initialize the backend
-	backend, err := backend.New(opts.urlsPath, opts.cacheDir, opts.resetCache)
+	backend, err := backend.New(opts.urlsPath, opts.cacheDir, opts.resetCache, nil)
 	if err != nil {
 		log.Println("Failed to initialize backend: ", err)
 		return err
 	}
 
+	backend.UrlsReadOnly = opts.urlsReadOnly
+
 	// Load the OPML file
 	if opts.loadOPMLFrom != "" {
 		log.Println("Loading OPML file: ", opts.loadOPMLFrom)
+		if backend.ReadOnly && !opts.dryRun {
+			return errors.New("another goread instance is running, close it before importing feeds")
+		}
 
-		if err := backend.Rss.LoadOPML(opts.loadOPMLFrom); err != nil {
+		changes, err := backend.Rss.LoadOPML(opts.loadOPMLFrom, opts.dryRun, opts.allowCommands)
+		if err != nil {
 			return err
 		}
 
-		fmt.Println(msgStyle.Render("Loaded OPML file successfully"))
-		return backend.Close(opts.urlsReadOnly)
+		printImportChanges(changes, opts.dryRun)
+		if opts.dryRun {
+			// NOTE: The urls didn't change, there is no need to rewrite them
+			backend.UrlsReadOnly = true
+		}
+
+		return backend.Close()
 	}
 
 	// Export the OPML file
@@ -193,17 +301,148 @@ func Run() error {
 		}
 
 		fmt.Println(msgStyle.Render("Exported OPML file successfully"))
-		return backend.Close(opts.urlsReadOnly)
+		return backend.Close()
 	}
 
 	// Create the browser
 	browser := browser.New(colors, backend)
-	if _, err = tea.NewProgram(browser).Run(); err != nil {
+	program := tea.NewProgram(browser)
+	go quitOnHangup(program)
+	if _, err = program.Run(); err != nil {
 		log.Println("Bubbletea program fail: ", err)
+
+		// NOTE: The changes made before the crash are still worth keeping
+		if closeErr := backend.Close(); closeErr != nil {
+			log.Println("Failed to save the changes: ", closeErr)
+		}
+
 		return err
 	}
 
 	// Clean up the backend
 	log.Println("Closing backend")
-	return backend.Close(opts.urlsReadOnly)
+	return backend.Close()
+}
+
+// logToFile sends the logs to the log file in the temporary directory, the returned function closes it
+func logToFile() func() {
+	f, err := tea.LogToFile(filepath.Join(os.TempDir(), "goread.log"), "")
+	if err != nil {
+		log.Println("Failed to create log file")
+		log.SetOutput(io.Discard)
+		return func() {}
+	}
+
+	return func() { f.Close() }
+}
+
+// quitOnHangup quits the program when the terminal is closed, so that the state is saved on the way out.
+// SIGINT and SIGTERM are already handled by bubbletea, which quits the same way.
+func quitOnHangup(program *tea.Program) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	<-hangup
+
+	log.Println("Received SIGHUP, quitting")
+	program.Quit()
+}
+
+// parseCacheDuration parses the cache duration flag, a plain number is treated as hours
+func parseCacheDuration(value string) (time.Duration, error) {
+	if hours, err := strconv.Atoi(value); err == nil {
+		if hours <= 0 {
+			return 0, fmt.Errorf("cmd.parseCacheDuration: the cache duration must be positive, got %d", hours)
+		}
+
+		return time.Duration(hours) * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("cmd.parseCacheDuration: %w", err)
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("cmd.parseCacheDuration: the cache duration must be positive, got %s", duration)
+	}
+
+	return duration, nil
+}
+
+// parseAutoRefresh parses the auto refresh flag, zero disables the background refresh
+func parseAutoRefresh(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("cmd.parseAutoRefresh: %w", err)
+	}
+
+	if interval < 0 {
+		return 0, fmt.Errorf("cmd.parseAutoRefresh: the interval can't be negative, got %s", interval)
+	}
+
+	return interval, nil
+}
+
+// parseAge parses the flags which set how long something is kept, a plain number is treated as days and zero means forever
+func parseAge(value string) (time.Duration, error) {
+	if days, err := strconv.Atoi(value); err == nil {
+		if days < 0 {
+			return 0, fmt.Errorf("cmd.parseAge: the age can't be negative, got %d", days)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("cmd.parseAge: %w", err)
+	}
+
+	if age < 0 {
+		return 0, fmt.Errorf("cmd.parseAge: the age can't be negative, got %s", age)
+	}
+
+	return age, nil
+}
+
+// printImportChanges prints what an OPML import did with every feed, followed by a summary
+func printImportChanges(changes []rss.ImportChange, dryRun bool) {
+	var added, renamed, skipped, refused int
+	for _, change := range changes {
+		fmt.Println(change)
+		switch change.Action {
+		case rss.ImportAdded:
+			added++
+
+		case rss.ImportRenamed:
+			renamed++
+
+		case rss.ImportSkipped:
+			skipped++
+
+		case rss.ImportRefused:
+			refused++
+		}
+	}
+
+	if refused > 0 {
+		fmt.Println(errStyle.Render(fmt.Sprintf(
+			"%d feeds run commands and weren't imported, use --allow_commands if you trust the file", refused,
+		)))
+	}
+
+	if dryRun {
+		fmt.Println(msgStyle.Render(fmt.Sprintf(
+			"Dry run: %d feeds would be added, %d renamed and %d skipped", added, renamed, skipped,
+		)))
+		return
+	}
+
+	fmt.Println(msgStyle.Render(fmt.Sprintf(
+		"Loaded OPML file successfully: %d feeds added, %d renamed and %d skipped", added, renamed, skipped,
+	)))
 }
@@ -0,0 +1,65 @@
+// Package atomicfile writes files so that a crash or an interrupted write never leaves them half-written.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes the data to a temporary file in the same directory and renames it over the
+// destination, so the file either has the old or the new contents. The directory is created if needed.
+// If the destination is a symlink, the file it points to is replaced and the link is kept.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	path, err := resolve(path)
+	if err != nil {
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	// NOTE: After a successful rename the temporary file is gone, so removing it is a no-op
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("atomicfile.WriteFile: %w", err)
+	}
+
+	return nil
+}
+
+// resolve follows the symlinks in the path, a path which doesn't exist yet is returned as is
+func resolve(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return path, nil
+	}
+
+	return resolved, err
+}
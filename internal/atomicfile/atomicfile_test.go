@@ -0,0 +1,68 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestWriteFile if we get an error then the file isn't replaced or the temporary files are left behind
+func TestWriteFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	path := filepath.Join(dir, "cache.json")
+
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("couldn't write the file: %v", err)
+		}
+
+		written, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("couldn't read the file: %v", err)
+		}
+
+		if string(written) != data {
+			t.Errorf("expected %q, got %q", data, written)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("couldn't read the directory: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("expected only the written file in the directory, got %d entries", len(entries))
+	}
+}
+
+// TestWriteFileSymlink if we get an error then writing through a symlink replaces the link with a regular file
+func TestWriteFileSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs extra privileges on windows")
+	}
+
+	dotfiles := t.TempDir()
+	target := filepath.Join(dotfiles, "urls.yml")
+	if err := os.WriteFile(target, []byte("old"), 0600); err != nil {
+		t.Fatalf("couldn't write the target: %v", err)
+	}
+
+	link := filepath.Join(t.TempDir(), "urls.yml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("couldn't create the symlink: %v", err)
+	}
+
+	if err := WriteFile(link, []byte("new"), 0600); err != nil {
+		t.Fatalf("couldn't write the file: %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the symlink to be kept, got %v (%v)", info, err)
+	}
+
+	if written, err := os.ReadFile(target); err != nil || string(written) != "new" {
+		t.Errorf("expected the target to be replaced, got %q (%v)", written, err)
+	}
+}
@@ -4,45 +4,82 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/mmcdole/gofeed"
 
 	"github.com/TypicalAM/goread/internal/backend/cache"
+	"github.com/TypicalAM/goread/internal/backend/export"
 	"github.com/TypicalAM/goread/internal/backend/rss"
+	"github.com/TypicalAM/goread/internal/filelock"
+	"github.com/TypicalAM/goread/internal/migrate"
 	"github.com/TypicalAM/goread/internal/ui/simplelist"
 )
 
+// ErrReadOnly is returned when a change can't be written to disk, because another instance uses the files
+var ErrReadOnly = errors.New("another goread instance is running, changes made here aren't written to disk")
+
 // Backend provides a way of fetching data from the cache and the RSS feed.
 type Backend struct {
-	Rss        *rss.Rss
-	Cache      *cache.Cache
-	ReadStatus *cache.ReadStatus
+	Rss          *rss.Rss
+	Cache        *cache.Cache
+	ReadStatus   *cache.ReadStatus
+	UrlsReadOnly bool
+	ReadOnly     bool
+	locks        []*filelock.Lock
+	saves        *saves
+}
+
+// saves serializes the writes made by the backend, the autosaves run in the background and
+// mustn't interleave or let an older snapshot of the urls file overwrite a newer one
+type saves struct {
+	mu      sync.Mutex
+	taken   uint64 // the generation of the last snapshot of the urls file
+	written uint64 // the generation of the last snapshot written to disk
 }
 
-// New creates a new backend and its components.
-func New(urlPath, cacheDir string, resetCache bool) (*Backend, error) {
+// New creates a new backend and its components. The fetcher is used for all the requests made by
+// the cache, if it is nil the default http client is used. If another instance already uses the
+// cache or the urls file, the backend is opened read-only and never writes to them.
+func New(urlPath, cacheDir string, resetCache bool, fetcher cache.Fetcher) (*Backend, error) {
 	log.Println("Creating new backend")
 	store, err := cache.New(cacheDir)
 	if err != nil {
 		return nil, fmt.Errorf("backend.New: %w", err)
 	}
 
+	if fetcher != nil {
+		store.Fetcher = fetcher
+	}
+
 	readStatus, err := cache.NewReadStatus(cacheDir)
 	if err != nil {
 		return nil, fmt.Errorf("backend.New: %w", err)
 	}
 
 	if !resetCache {
+		// NOTE: Files from a newer goread or ones which couldn't be backed up would be overwritten with what this version understands
 		if err = store.Load(); err != nil {
 			log.Println("Cache load failed: ", err)
+			if errors.Is(err, migrate.ErrTooNew) || errors.Is(err, migrate.ErrBackupFailed) {
+				return nil, fmt.Errorf("backend.New: %w", err)
+			}
 		}
 
 		if err = readStatus.Load(); err != nil {
 			log.Println("Read status load failed: ", err)
+			if errors.Is(err, migrate.ErrTooNew) || errors.Is(err, migrate.ErrBackupFailed) {
+				return nil, fmt.Errorf("backend.New: %w", err)
+			}
 		}
+
+		readStatus.ConvertLegacy(store)
 	}
 
 	rss, err := rss.New(urlPath)
@@ -55,7 +92,51 @@ func New(urlPath, cacheDir string, resetCache bool) (*Backend, error) {
 		return nil, fmt.Errorf("backend.New: %w", err)
 	}
 
-	return &Backend{rss, store, readStatus}, nil
+	backend := &Backend{Rss: rss, Cache: store, ReadStatus: readStatus, saves: &saves{}}
+	backend.lock(store.Dir(), rss.Dir())
+	return backend, nil
+}
+
+// lock takes the locks on the data directories, if any of them is held by another instance the
+// backend becomes read-only and lets go of the other locks
+func (b *Backend) lock(dirs ...string) {
+	locked := make(map[string]struct{})
+	for _, dir := range dirs {
+		if _, ok := locked[dir]; ok {
+			continue
+		}
+
+		lock, err := filelock.TryLock(dir)
+		if errors.Is(err, filelock.ErrLocked) {
+			log.Println("The directory", dir, "is used by another instance, opening read-only")
+			b.ReadOnly = true
+			break
+		}
+
+		// NOTE: A directory we can't lock (like a read-only one) shouldn't keep goread from starting
+		if err != nil {
+			log.Println("Couldn't lock the directory", dir, err)
+			continue
+		}
+
+		locked[dir] = struct{}{}
+		b.locks = append(b.locks, lock)
+	}
+
+	if b.ReadOnly {
+		b.unlock()
+	}
+}
+
+// unlock releases the locks on the data directories
+func (b *Backend) unlock() {
+	for _, lock := range b.locks {
+		if err := lock.Unlock(); err != nil {
+			log.Println("Couldn't release the lock", err)
+		}
+	}
+
+	b.locks = nil
 }
 
 // FetchCategories gets the categories.
@@ -70,17 +151,29 @@ func (b Backend) FetchCategories(_ string) tea.Cmd {
 	}
 }
 
-// FetchFeeds gets the feeds from a category.
+// FetchFeeds gets the subcategories and the feeds from a category, the names of the subcategories end with
+// the path separator.
 func (b Backend) FetchFeeds(catname string) tea.Cmd {
 	return func() tea.Msg {
-		feeds, err := b.Rss.GetFeeds(catname)
+		cat, err := b.Rss.GetCategory(catname)
 		if err != nil {
 			return FetchErrorMsg{err, "Error while trying to get feeds"}
 		}
 
-		items := make([]list.Item, len(feeds))
-		for i, feed := range feeds {
-			items[i] = simplelist.NewItem(feed.Name, feed.URL)
+		items := make([]list.Item, 0, len(cat.Categories)+len(cat.Subscriptions))
+		for _, sub := range cat.Categories {
+			items = append(items, simplelist.NewItem(sub.Name+rss.PathSeparator, sub.Description))
+		}
+
+		for _, feed := range cat.Subscriptions {
+			item := simplelist.NewItem(feed.Name, feed.URL)
+			if health, ok := b.Cache.GetHealth(feed.URL); ok && health.Failing() {
+				item = item.WithStatus(healthStatus(health))
+			} else if others := b.otherLocations(catname, feed); len(others) > 0 {
+				item = item.WithStatus("⚠ also subscribed to as " + strings.Join(others, ", "))
+			}
+
+			items = append(items, item)
 		}
 
 		return FetchSuccessMsg{items}
@@ -107,7 +200,7 @@ func (b Backend) FetchArticles(feedname string, refresh bool) tea.Cmd {
 // FetchAllArticles gets all the articles from all the feeds.
 func (b Backend) FetchAllArticles(_ string, refresh bool) tea.Cmd {
 	return func() tea.Msg {
-		return b.articlesToSuccessMsg(b.Cache.GetArticlesBulk(b.Rss.GetAllFeeds(), refresh))
+		return b.articlesToSuccessMsg(b.Cache.GetArticlesBulk(b.Rss.GetAllFeedsUnique(), refresh))
 	}
 }
 
@@ -118,6 +211,91 @@ func (b Backend) FetchDownloadedArticles(_ string, _ bool) tea.Cmd {
 	}
 }
 
+// RefreshExpired re-fetches the expired feeds in the background and reports which feeds got new articles.
+func (b Backend) RefreshExpired() tea.Cmd {
+	// NOTE: The feeds are read here, the urls file can be edited while the refresh is running
+	feeds := b.Rss.GetAllFeeds()
+	return func() tea.Msg {
+		if b.Cache.OfflineMode {
+			return FeedsRefreshedMsg{}
+		}
+
+		byURL := b.Cache.RefreshExpired(feeds)
+
+		newArticles := make(map[string]int)
+		for _, feed := range feeds {
+			if count := byURL[feed.URL]; count > 0 {
+				newArticles[feed.Name] += count
+			}
+		}
+
+		return FeedsRefreshedMsg{newArticles}
+	}
+}
+
+// ReloadArticles gets the cached articles of a feed, so that an open tab can be updated in place.
+func (b Backend) ReloadArticles(feedName string) tea.Cmd {
+	return func() tea.Msg { return b.reloadArticles(feedName) }
+}
+
+// reloadArticles returns the cached articles of a feed as an ArticlesReloadedMsg, nil if the feed can't be read
+func (b Backend) reloadArticles(feedName string) tea.Msg {
+	var items cache.SortableArticles
+
+	switch feedName {
+	case rss.AllFeedsName:
+		items = b.Cache.GetArticlesBulk(b.Rss.GetAllFeedsUnique(), false)
+
+	default:
+		feed, err := b.Rss.GetFeed(feedName)
+		if err != nil {
+			log.Println("Cannot reload feed", feedName, err)
+			return nil
+		}
+
+		items, err = b.Cache.GetArticles(feed, false)
+		if err != nil {
+			log.Println("Cannot reload feed", feedName, err)
+			return nil
+		}
+	}
+
+	return ArticlesReloadedMsg{feedName, b.articlesToSuccessMsg(items).Items}
+}
+
+// ExtractNewArticles extracts the full content of the new articles of a feed which asks for it, the open tab
+// is reloaded once they are extracted. It is sent after the articles are shown, so the pages don't hold them up.
+func (b Backend) ExtractNewArticles(feedName string) tea.Cmd {
+	return func() tea.Msg {
+		feed, err := b.Rss.GetFeed(feedName)
+		if err != nil || !feed.FullContent {
+			return nil
+		}
+
+		if b.Cache.ExtractNew(feed) == 0 {
+			return nil
+		}
+
+		return b.reloadArticles(feedName)
+	}
+}
+
+// DiscoverFeeds looks for the feeds available at the url, so that a website can be added instead of a feed.
+func (b Backend) DiscoverFeeds(category, name, url string) tea.Cmd {
+	return func() tea.Msg {
+		if b.Cache.OfflineMode {
+			return FeedsDiscoveredMsg{category, name, []cache.Candidate{{URL: url}}}
+		}
+
+		candidates, err := b.Cache.Discover(url)
+		if err != nil {
+			return FetchErrorMsg{err, "Error while looking for feeds"}
+		}
+
+		return FeedsDiscoveredMsg{category, name, candidates}
+	}
+}
+
 // DownloadItem downloads an article.
 func (b Backend) DownloadItem(feedName string, index int) tea.Cmd {
 	return func() tea.Msg {
@@ -126,18 +304,169 @@ func (b Backend) DownloadItem(feedName string, index int) tea.Cmd {
 			return FetchErrorMsg{err, "Error while getting the article"}
 		}
 
-		b.Cache.AddToDownloaded(*item)
+		var opts rss.FetchOptions
+		if feed, err := b.Rss.GetFeed(feedName); err == nil {
+			opts = feed.FetchOptions
+		}
+
+		// Saved articles are written to disk right away, so that they survive a crash
+		saved := *item
+		b.Cache.SaveOffline(&saved, opts)
+		b.Cache.AddToDownloaded(saved)
+		if b.ReadOnly {
+			return AutosaveFailedMsg{fmt.Errorf("backend.DownloadItem: %w", ErrReadOnly)}
+		}
+
+		if err = b.Cache.Save(); err != nil {
+			return AutosaveFailedMsg{fmt.Errorf("backend.DownloadItem: %w", err)}
+		}
+
 		return nil
 	}
 }
 
-// Close closes the backend and saves its components.
-func (b Backend) Close(urlsReadOnly bool) error {
-	if !urlsReadOnly {
+// ExtractArticle fetches the full content of an article from its page.
+func (b Backend) ExtractArticle(feedName string, index int) tea.Cmd {
+	return func() tea.Msg {
+		item, err := b.indexToItem(feedName, index)
+		if err != nil {
+			return FetchErrorMsg{err, "Error while getting the article"}
+		}
+
+		var opts rss.FetchOptions
+		if feed, err := b.Rss.GetFeed(feedName); err == nil {
+			opts = feed.FetchOptions
+		}
+
+		content, err := b.Cache.GetFullContent(item.Link, opts)
+		if err != nil {
+			return FetchErrorMsg{err, "Error while extracting the article"}
+		}
+
+		return ArticleExtractedMsg{item.Link, rss.YassifyFullItem(item, content)}
+	}
+}
+
+// ExportSaved writes the saved articles which pass the filter to the path, the amount of exported articles is returned.
+func (b Backend) ExportSaved(path string, format export.Format, filter export.Filter) (int, error) {
+	feedNames := make(map[string]string)
+	for _, feed := range b.Rss.GetAllFeeds() {
+		feedNames[feed.URL] = feed.Name
+	}
+
+	saved := b.Cache.GetDownloaded()
+	articles := make([]export.Article, 0, len(saved))
+	for i := range saved {
+		feedURL := cache.NewArticleID(&saved[i]).Feed
+		markdown := rss.YassifyItem(&saved[i])
+		if content, ok := b.Cache.GetExtracted(saved[i].Link); ok {
+			markdown = rss.YassifyFullItem(&saved[i], content)
+		}
+
+		article := export.Article{
+			Item:     &saved[i],
+			FeedName: feedNames[feedURL],
+			FeedURL:  feedURL,
+			Markdown: markdown,
+			Tags:     cache.Tags(&saved[i]),
+			Note:     cache.Note(&saved[i]),
+		}
+
+		if filter.Matches(article) {
+			articles = append(articles, article)
+		}
+	}
+
+	if err := export.Write(articles, format, path); err != nil {
+		return 0, fmt.Errorf("backend.ExportSaved: %w", err)
+	}
+
+	return len(articles), nil
+}
+
+// ExportDownloaded exports all the saved articles to a single html file in the working directory.
+func (b Backend) ExportDownloaded() tea.Cmd {
+	return func() tea.Msg {
+		path, err := filepath.Abs(fmt.Sprintf("goread-saved-%s.html", time.Now().Format("2006-01-02")))
+		if err != nil {
+			return FetchErrorMsg{err, "Error while exporting the saved articles"}
+		}
+
+		count, err := b.ExportSaved(path, export.HTML, export.Filter{})
+		if err != nil {
+			return FetchErrorMsg{err, "Error while exporting the saved articles"}
+		}
+
+		return SavedExportedMsg{path, count}
+	}
+}
+
+// Autosave writes the cache and the read status to disk if they changed since the last save. The urls
+// file is only written when asked to, since it only changes when the user edits the feeds. The feeds are
+// copied when the command is made, so the write doesn't race with the edits made in the meantime.
+func (b Backend) Autosave(urls bool) tea.Cmd {
+	var snapshot *rss.Rss
+	var generation uint64
+	if urls && !b.UrlsReadOnly && !b.ReadOnly {
+		b.saves.mu.Lock()
+		b.saves.taken++
+		snapshot, generation = b.Rss.Snapshot(), b.saves.taken
+		b.saves.mu.Unlock()
+	}
+
+	return func() tea.Msg {
+		if b.ReadOnly {
+			return nil
+		}
+
+		b.saves.mu.Lock()
+		defer b.saves.mu.Unlock()
+
+		// NOTE: A newer snapshot could have been written before this one
+		if snapshot != nil && generation > b.saves.written {
+			if err := snapshot.Save(); err != nil {
+				return AutosaveFailedMsg{fmt.Errorf("backend.Autosave: %w", err)}
+			}
+
+			b.saves.written = generation
+		}
+
+		if b.Cache.Dirty() {
+			if err := b.Cache.Save(); err != nil {
+				return AutosaveFailedMsg{fmt.Errorf("backend.Autosave: %w", err)}
+			}
+		}
+
+		if b.ReadStatus.Dirty() {
+			if err := b.ReadStatus.Save(); err != nil {
+				return AutosaveFailedMsg{fmt.Errorf("backend.Autosave: %w", err)}
+			}
+		}
+
+		return nil
+	}
+}
+
+// Close closes the backend and saves its components, a read-only backend doesn't save anything.
+func (b Backend) Close() error {
+	defer b.unlock()
+	if b.ReadOnly {
+		log.Println("The backend is read-only, not saving")
+		return nil
+	}
+
+	b.saves.mu.Lock()
+	defer b.saves.mu.Unlock()
+
+	if !b.UrlsReadOnly {
 		if err := b.Rss.Save(); err != nil {
 			return fmt.Errorf("backend.Close: %w", err)
 		}
+
+		b.saves.taken++
+		b.saves.written = b.saves.taken
 	}
+
 	if err := b.Cache.Save(); err != nil {
 		return fmt.Errorf("backend.Close: %w", err)
 	}
@@ -172,15 +501,29 @@ func (b Backend) articlesToSuccessMsg(items cache.SortableArticles) FetchArticle
 
 		if alreadySaved {
 			item.Title = "↓ " + item.Title
-		} else if b.ReadStatus.IsRead(item.Link) {
+		} else if b.ReadStatus.IsRead(cache.NewArticleID(&items[i])) {
 			item.Title = "✓ " + item.Title
 		}
 
+		markdown := rss.YassifyItem(&items[i])
+		if content, ok := b.Cache.GetExtracted(item.Link); ok {
+			markdown = rss.YassifyFullItem(&items[i], content)
+		}
+
+		tags, note := cache.Tags(&items[i]), cache.Note(&items[i])
+		desc := betterDesc(item.Description)
+		if len(tags) != 0 {
+			desc = "#" + strings.Join(tags, " #") + " " + desc
+		}
+
 		result[i] = ArticleItem{
 			ArtTitle:        item.Title,
-			RawDesc:         betterDesc(item.Description),
-			MarkdownContent: rss.YassifyItem(&items[i]),
+			RawDesc:         desc,
+			MarkdownContent: markdown + annotationMarkdown(tags, note),
 			FeedURL:         item.Link,
+			ID:              cache.NewArticleID(&items[i]),
+			Tags:            tags,
+			Note:            note,
 		}
 	}
 
@@ -193,7 +536,7 @@ func (b Backend) indexToItem(feedName string, index int) (*gofeed.Item, error) {
 
 	switch feedName {
 	case rss.AllFeedsName:
-		articles = b.Cache.GetArticlesBulk(b.Rss.GetAllFeeds(), false)
+		articles = b.Cache.GetArticlesBulk(b.Rss.GetAllFeedsUnique(), false)
 
 	case rss.DownloadedFeedsName:
 		articles = b.Cache.GetDownloaded()
@@ -214,6 +557,52 @@ func (b Backend) indexToItem(feedName string, index int) (*gofeed.Item, error) {
 	return &articles[index], nil
 }
 
+// annotationMarkdown shows the tags and the note of a saved article below its content.
+func annotationMarkdown(tags []string, note string) string {
+	if len(tags) == 0 && note == "" {
+		return ""
+	}
+
+	mdown := "## Notes\n"
+	if len(tags) != 0 {
+		mdown += "Tags: #" + strings.Join(tags, " #") + "\n\n"
+	}
+
+	if note != "" {
+		mdown += "> " + strings.ReplaceAll(note, "\n", "\n> ") + "\n"
+	}
+
+	return mdown + "\n"
+}
+
+// otherLocations returns the paths of the other subscriptions of the feed url
+func (b Backend) otherLocations(catname string, feed rss.Feed) []string {
+	var result []string
+	for _, loc := range b.Rss.FindURL(feed.URL) {
+		if loc.Name == feed.Name && strings.Trim(loc.Category, rss.PathSeparator) == strings.Trim(catname, rss.PathSeparator) {
+			continue
+		}
+
+		result = append(result, loc.String())
+	}
+
+	return result
+}
+
+// healthStatus describes the health of a failing feed.
+func healthStatus(health cache.Health) string {
+	status := fmt.Sprintf("⚠ failed %d times", health.ConsecutiveFailures)
+	if health.ConsecutiveFailures == 1 {
+		status = "⚠ failed once"
+	}
+
+	if !health.LastSuccess.IsZero() {
+		status += ", last success " + health.LastSuccess.Format("2006-01-02 15:04")
+	}
+
+	return status + ": " + health.LastError
+}
+
 // betterDesc returns a styled item description.
 func betterDesc(rawDesc string) string {
 	desc := rawDesc
@@ -1,10 +1,20 @@
 package backend
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/mmcdole/gofeed"
+
+	"github.com/TypicalAM/goread/internal/backend/export"
 	"github.com/TypicalAM/goread/internal/backend/rss"
 )
 
@@ -27,9 +37,14 @@ func testOffline() bool {
 	return false
 }
 
-// getBackend creates a fake backend
-func getBackend() (*Backend, error) {
-	b, err := New("../test/data/urls.yml", "", false)
+// getBackend creates a fake backend, the urls are copied to a temporary directory so that the test data isn't locked
+func getBackend(t *testing.T) (*Backend, error) {
+	urlsPath, err := copyUrls(t.TempDir())
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := New(urlsPath, t.TempDir(), false, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -37,10 +52,21 @@ func getBackend() (*Backend, error) {
 	return b, err
 }
 
+// copyUrls copies the test urls file to the directory
+func copyUrls(dir string) (string, error) {
+	data, err := os.ReadFile("../test/data/urls.yml")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "urls.yml")
+	return path, os.WriteFile(path, data, 0600)
+}
+
 // TestBackendLoad if we get an error loading doesn't work
 func TestBackendLoad(t *testing.T) {
 	// Create a backend with a valid file
-	b, err := getBackend()
+	b, err := getBackend(t)
 	if err != nil {
 		t.Errorf("couldn't get the urls from the file")
 	}
@@ -54,7 +80,7 @@ func TestBackendLoad(t *testing.T) {
 // TestBackendGetCategories if we get an error getting items doesn't work
 func TestBackendGetCategories(t *testing.T) {
 	// Create a backend with a valid file
-	b, err := getBackend()
+	b, err := getBackend(t)
 	if err != nil {
 		t.Errorf("couldn't get the urls from the file")
 	}
@@ -73,7 +99,7 @@ func TestBackendGetCategories(t *testing.T) {
 // TestBackendGetFeeds if we get an error getting feeds from a category doesn't work
 func TestBackendGetFeeds(t *testing.T) {
 	// Create a backend with a valid file
-	b, err := getBackend()
+	b, err := getBackend(t)
 	if err != nil {
 		t.Errorf("couldn't get the urls from the file")
 	}
@@ -107,6 +133,20 @@ func TestBackendGetFeeds(t *testing.T) {
 	default:
 		t.Errorf("expected FetchErrorMessage, got %T", msg)
 	}
+
+	// The subcategories are listed before the feeds and can be fetched by their path
+	if err = b.Rss.AddSubcategory("News", "World", "World news"); err != nil {
+		t.Fatalf("couldn't add a subcategory: %v", err)
+	}
+
+	msg, ok := b.FetchFeeds("News")().(FetchSuccessMsg)
+	if !ok || len(msg.Items) != 2 || msg.Items[0].FilterValue() != "World"+rss.PathSeparator {
+		t.Errorf("expected the subcategory to be listed first, got %v", msg.Items)
+	}
+
+	if _, ok = b.FetchFeeds(rss.JoinPath("News", "World"))().(FetchSuccessMsg); !ok {
+		t.Errorf("expected the subcategory to be fetched by its path")
+	}
 }
 
 // TestBackendGetArticles if we get an error getting items from a feed doesn't work
@@ -118,7 +158,7 @@ func TestBackendGetArticles(t *testing.T) {
 	}
 
 	// Create a backend with a valid file
-	b, err := getBackend()
+	b, err := getBackend(t)
 	if err != nil {
 		t.Errorf("couldn't get the urls from the file")
 	}
@@ -157,3 +197,206 @@ func TestBackendGetArticles(t *testing.T) {
 		t.Errorf("expected FetchErrorMessage, got %T", msg)
 	}
 }
+
+// redirectFetcher sends all the requests to a test server instead of the real hosts
+type redirectFetcher struct{ target *url.URL }
+
+// Do fulfills the cache.Fetcher interface
+func (f redirectFetcher) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = f.target.Scheme
+	req.URL.Host = f.target.Host
+	return http.DefaultClient.Do(req)
+}
+
+// TestBackendFetcher if we get an error the backend doesn't use the fetcher it was given
+func TestBackendFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>
+<item><title>Served from %s</title><link>https://example.com/1</link></item></channel></rss>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("couldn't parse the server url: %v", err)
+	}
+
+	urlsPath, err := copyUrls(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't copy the urls: %v", err)
+	}
+
+	b, err := New(urlsPath, t.TempDir(), true, redirectFetcher{target})
+	if err != nil {
+		t.Fatalf("couldn't create the backend: %v", err)
+	}
+
+	result := b.FetchArticles("Primordial soup", false)()
+	msg, ok := result.(FetchArticleSuccessMsg)
+	if !ok {
+		t.Fatalf("expected FetchArticleSuccessMsg, got %T", result)
+	}
+
+	if len(msg.Items) != 1 || msg.Items[0].FilterValue() != "Served from /feed" {
+		t.Errorf("expected the article from the test server, got %v", msg.Items)
+	}
+}
+
+// TestBackendReadOnly if we get an error then a second instance isn't read-only or it overwrites the files
+func TestBackendReadOnly(t *testing.T) {
+	cacheDir := t.TempDir()
+	urlsPath, err := copyUrls(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't copy the urls: %v", err)
+	}
+
+	first, err := New(urlsPath, cacheDir, false, nil)
+	if err != nil {
+		t.Fatalf("couldn't create the backend: %v", err)
+	}
+
+	second, err := New(urlsPath, cacheDir, false, nil)
+	if err != nil {
+		t.Fatalf("couldn't create the backend: %v", err)
+	}
+
+	if first.ReadOnly || !second.ReadOnly {
+		t.Fatalf("expected only the second backend to be read-only")
+	}
+
+	if err = second.Rss.AddCategory("Second", ""); err != nil {
+		t.Fatalf("couldn't add a category: %v", err)
+	}
+
+	// Saving an article can't succeed silently, it is only kept until the backend is closed
+	second.Cache.AddToDownloaded(gofeed.Item{Title: "Saved", Link: "https://example.com/saved"})
+	msg, ok := second.DownloadItem(rss.DownloadedFeedsName, 0)().(AutosaveFailedMsg)
+	if !ok || !errors.Is(msg.Err, ErrReadOnly) {
+		t.Errorf("expected saving an article in a read-only backend to fail, got %v", msg)
+	}
+
+	if err = second.Close(); err != nil {
+		t.Fatalf("couldn't close the backend: %v", err)
+	}
+
+	if err = first.Close(); err != nil {
+		t.Fatalf("couldn't close the backend: %v", err)
+	}
+
+	third, err := New(urlsPath, cacheDir, false, nil)
+	if err != nil {
+		t.Fatalf("couldn't create the backend: %v", err)
+	}
+	defer third.Close()
+
+	if third.ReadOnly {
+		t.Errorf("expected the backend to be writable once the others are closed")
+	}
+
+	if _, err = third.Rss.GetFeeds("Second"); err == nil {
+		t.Errorf("expected the changes of the read-only backend not to be saved")
+	}
+}
+
+// TestBackendAutosave if we get an error then an autosave wrote the feeds as they were when it ran or let
+// an older snapshot overwrite a newer one
+func TestBackendAutosave(t *testing.T) {
+	urlsPath, err := copyUrls(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't copy the urls: %v", err)
+	}
+
+	b, err := New(urlsPath, t.TempDir(), false, nil)
+	if err != nil {
+		t.Fatalf("couldn't create the backend: %v", err)
+	}
+	defer b.Close()
+
+	if err = b.Rss.AddCategory("First", ""); err != nil {
+		t.Fatalf("couldn't add a category: %v", err)
+	}
+
+	older := b.Autosave(true)
+	if err = b.Rss.AddCategory("Second", ""); err != nil {
+		t.Fatalf("couldn't add a category: %v", err)
+	}
+
+	newer := b.Autosave(true)
+	if err = b.Rss.AddCategory("Third", ""); err != nil {
+		t.Fatalf("couldn't add a category: %v", err)
+	}
+
+	if msg := newer(); msg != nil {
+		t.Fatalf("expected the autosave to succeed, got %v", msg)
+	}
+
+	if msg := older(); msg != nil {
+		t.Fatalf("expected the autosave to succeed, got %v", msg)
+	}
+
+	saved, err := rss.New(urlsPath)
+	if err != nil {
+		t.Fatalf("couldn't create the rss: %v", err)
+	}
+
+	if err = saved.Load(); err != nil {
+		t.Fatalf("couldn't load the rss: %v", err)
+	}
+
+	if _, err = saved.GetFeeds("Second"); err != nil {
+		t.Errorf("expected the newer snapshot to be kept, got %v", err)
+	}
+
+	if _, err = saved.GetFeeds("Third"); err == nil {
+		t.Errorf("expected the category added after the snapshot not to be saved")
+	}
+}
+
+// TestBackendExportSaved if we get an error then the saved articles aren't filtered by the feed name
+func TestBackendExportSaved(t *testing.T) {
+	b, err := getBackend(t)
+	if err != nil {
+		t.Fatalf("couldn't get the backend: %v", err)
+	}
+	defer b.Close()
+
+	for _, feedURL := range []string{"https://primordialsoup.info/feed", "https://example.com/unknown"} {
+		b.Cache.AddToDownloaded(gofeed.Item{
+			Title:  "Saved from " + feedURL,
+			Link:   feedURL + "/article",
+			Custom: map[string]string{"goread:feed": feedURL},
+		})
+	}
+
+	dir := filepath.Join(t.TempDir(), "export")
+	filter := export.Filter{Feeds: []string{"Primordial soup"}}
+	count, err := b.ExportSaved(dir, export.Markdown, filter)
+	if err != nil {
+		t.Fatalf("couldn't export the saved articles: %v", err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected one exported article, got %d", count)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "saved-from-https-primordialsoup-info-feed.md"))
+	if err != nil {
+		t.Fatalf("couldn't read the exported article: %v", err)
+	}
+
+	if !strings.Contains(string(data), "feed: Primordial soup\n") {
+		t.Errorf("expected the feed name in the front matter, got:\n%s", data)
+	}
+}
+
+// TestBackendFilterValueTags if we get an error then the tags can't be read back from the filter value of an article
+func TestBackendFilterValueTags(t *testing.T) {
+	item := ArticleItem{ArtTitle: "Title with #hash\n#fake", Tags: []string{"go", "machine learning"}}
+	if tags := FilterValueTags(item.FilterValue()); !reflect.DeepEqual(tags, item.Tags) {
+		t.Errorf("expected the tags %v, got %v", item.Tags, tags)
+	}
+
+	if tags := FilterValueTags(ArticleItem{ArtTitle: "Untagged"}.FilterValue()); len(tags) != 0 {
+		t.Errorf("expected no tags, got %v", tags)
+	}
+}
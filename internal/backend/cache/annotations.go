@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// customTags is the key under which the comma separated tags of a saved article are kept in its custom elements
+const customTags = "goread:tags"
+
+// customNote is the key under which the note of a saved article is kept in its custom elements
+const customNote = "goread:note"
+
+// Tags returns the tags attached to a saved article
+func Tags(item *gofeed.Item) []string {
+	return ParseTags(item.Custom[customTags])
+}
+
+// Note returns the note attached to a saved article
+func Note(item *gofeed.Item) string {
+	return item.Custom[customNote]
+}
+
+// HasTag checks if the tag is one of the tags of a saved article, tags are matched case-insensitively
+// and the leading hash is ignored
+func HasTag(tags []string, tag string) bool {
+	tag = normalizeTag(tag)
+	for _, candidate := range tags {
+		if strings.EqualFold(normalizeTag(candidate), tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseTags splits a comma separated list of tags, the empty and repeated tags are dropped
+func ParseTags(value string) []string {
+	var tags []string
+	seen := make(map[string]struct{})
+	for _, tag := range strings.Split(value, ",") {
+		tag = normalizeTag(tag)
+		if _, ok := seen[strings.ToLower(tag)]; ok || tag == "" {
+			continue
+		}
+
+		seen[strings.ToLower(tag)] = struct{}{}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// normalizeTag trims the tag, the leading hash is optional so that "#go" and "go" are the same tag
+func normalizeTag(tag string) string {
+	return strings.TrimPrefix(strings.TrimSpace(tag), "#")
+}
+
+// Annotate sets the tags and the note of the saved article with the given id, an article saved more than
+// once gets them on every copy
+func (c *Cache) Annotate(id ArticleID, tags []string, note string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	found := false
+	for i := range c.Downloaded {
+		if item := &c.Downloaded[i]; articleKey(item) == id.Key && articleFeed(item) == id.Feed {
+			annotate(item, tags, note)
+			found = true
+		}
+	}
+
+	if !found {
+		return errors.New("the article isn't saved")
+	}
+
+	c.dirty = true
+	return nil
+}
+
+// annotate replaces the tags and the note kept in the custom elements of an article
+func annotate(item *gofeed.Item, tags []string, note string) {
+	custom := make(map[string]string, len(item.Custom)+2)
+	for key, value := range item.Custom {
+		custom[key] = value
+	}
+
+	delete(custom, customTags)
+	delete(custom, customNote)
+	if tags = ParseTags(strings.Join(tags, ",")); len(tags) != 0 {
+		custom[customTags] = strings.Join(tags, ",")
+	}
+
+	if note = strings.TrimSpace(note); note != "" {
+		custom[customNote] = note
+	}
+
+	item.Custom = custom
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/TypicalAM/goread/internal/backend/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+// DefaultSavePages decides if the full article page is saved along with the images when an article is saved
+var DefaultSavePages = false
+
+// maxAssetSize is the maximum size of a single downloaded image
+const maxAssetSize = 20 << 20
+
+// customAssets is the key under which the asset directory of a saved article is kept in its custom elements
+const customAssets = "goread:assets"
+
+// markdownImagePattern matches the images in markdown, the first group is the image url
+var markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)`)
+
+// imageExtensions are the file extensions kept from the image urls, anything else is guessed from the content type
+var imageExtensions = map[string]struct{}{
+	".avif": {}, ".bmp": {}, ".gif": {}, ".jpeg": {}, ".jpg": {}, ".png": {}, ".svg": {}, ".webp": {},
+}
+
+// SaveOffline makes an article readable offline before it is saved. Its images are downloaded into the
+// cache directory and the article is pointed at the local copies. If DefaultSavePages is set, the full
+// article page is extracted and saved as well. Images which can't be downloaded are left remote.
+// The caller must not hold the lock.
+func (c *Cache) SaveOffline(item *gofeed.Item, opts rss.FetchOptions) {
+	if c.OfflineMode {
+		return
+	}
+
+	dirName := assetDirName(item)
+	dir, err := filepath.Abs(filepath.Join(c.Dir(), "assets", dirName))
+	if err != nil {
+		log.Println("Cannot save the article offline", item.Link, err)
+		return
+	}
+
+	base, _ := url.Parse(item.Link)
+	localize := func(rawURL string) string {
+		ref, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil {
+			return rawURL
+		}
+
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+
+		local, err := c.downloadAsset(dir, ref, opts)
+		if err != nil {
+			log.Println("Cannot save the image", ref, err)
+			return ref.String()
+		}
+
+		return local
+	}
+
+	item.Description = localizeHTML(item.Description, localize)
+	item.Content = localizeHTML(item.Content, localize)
+
+	content, ok := c.GetExtracted(item.Link)
+	if !ok && DefaultSavePages && item.Link != "" {
+		var err error
+		if content, err = c.GetFullContent(item.Link, opts); err == nil {
+			ok = true
+		} else {
+			log.Println("Cannot save the article page", item.Link, err)
+		}
+	}
+
+	if ok {
+		content = localizeMarkdown(content, localize)
+		c.mu.Lock()
+		c.Extracted[item.Link] = content
+		c.dirty = true
+		c.mu.Unlock()
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		custom := make(map[string]string, len(item.Custom)+1)
+		for key, value := range item.Custom {
+			custom[key] = value
+		}
+
+		custom[customAssets] = dirName
+		item.Custom = custom
+	}
+}
+
+// assetDirName returns the name of the directory holding the assets of an article
+func assetDirName(item *gofeed.Item) string {
+	sum := sha256.Sum256([]byte(articleFeed(item) + "\n" + articleKey(item)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// downloadAsset downloads a single image into the directory and returns the url of the local copy
+func (c *Cache) downloadAsset(dir string, ref *url.URL, opts rss.FetchOptions) (string, error) {
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return "", fmt.Errorf("unsupported url %q", ref)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(opts))
+	defer cancel()
+
+	req, err := newRequest(ctx, ref.String(), opts)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.fetcher().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", newStatusError(resp)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize))
+	if err != nil {
+		return "", err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(ref.String()))
+	name := hex.EncodeToString(sum[:8]) + assetExtension(ref, resp.Header.Get("Content-Type"))
+	if err = os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return "", err
+	}
+
+	// NOTE: Windows paths start with the drive letter, file urls need a slash before it
+	local := filepath.ToSlash(filepath.Join(dir, name))
+	if !strings.HasPrefix(local, "/") {
+		local = "/" + local
+	}
+
+	return (&url.URL{Scheme: "file", Path: local}).String(), nil
+}
+
+// assetExtension returns the file extension for an image, taken from its url or its content type
+func assetExtension(ref *url.URL, contentType string) string {
+	ext := strings.ToLower(path.Ext(ref.Path))
+	if _, ok := imageExtensions[ext]; ok {
+		return ext
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) != 0 {
+			return exts[0]
+		}
+	}
+
+	return ""
+}
+
+// localizeHTML replaces the image urls in the html using the localize function
+func localizeHTML(content string, localize func(string) string) string {
+	if !strings.Contains(content, "<img") {
+		return content
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content
+	}
+
+	doc.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+		img.SetAttr("src", localize(img.AttrOr("src", "")))
+		img.RemoveAttr("srcset")
+	})
+
+	html, err := doc.Find("body").Html()
+	if err != nil {
+		return content
+	}
+
+	return html
+}
+
+// localizeMarkdown replaces the image urls in the markdown using the localize function
+func localizeMarkdown(content string, localize func(string) string) string {
+	return markdownImagePattern.ReplaceAllStringFunc(content, func(image string) string {
+		match := markdownImagePattern.FindStringSubmatch(image)
+		return strings.TrimSuffix(image, match[1]) + localize(match[1])
+	})
+}
+
+// removeAssets removes the saved assets of an article, unless another saved copy of it still uses them.
+// The extracted content pointing at the assets is dropped too, it is extracted again when needed.
+// The caller must hold the lock.
+func (c *Cache) removeAssets(item *gofeed.Item) {
+	dirName := item.Custom[customAssets]
+	if dirName == "" {
+		return
+	}
+
+	for i := range c.Downloaded {
+		if c.Downloaded[i].Custom[customAssets] == dirName {
+			return
+		}
+	}
+
+	dir, err := filepath.Abs(filepath.Join(c.Dir(), "assets", dirName))
+	if err != nil {
+		log.Println("Cannot remove the assets of", item.Link, err)
+		return
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		log.Println("Cannot remove the assets of", item.Link, err)
+	}
+
+	if strings.Contains(c.Extracted[item.Link], filepath.ToSlash(dir)) {
+		delete(c.Extracted, item.Link)
+	}
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -8,12 +9,17 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
+	"github.com/TypicalAM/goread/internal/atomicfile"
+	"github.com/TypicalAM/goread/internal/backend/filter"
 	"github.com/TypicalAM/goread/internal/backend/rss"
 	"github.com/mmcdole/gofeed"
 )
@@ -21,8 +27,20 @@ import (
 // DefaultCacheDuration is the default duration for which an item is cached
 var DefaultCacheDuration = 24 * time.Hour
 
-// DefaultCacheSize is the default size of the cache
-var DefaultCacheSize = 100
+// DefaultCacheSize is the maximum amount of articles kept in the cache, the least recently used feeds are evicted first
+var DefaultCacheSize = 5000
+
+// DefaultFetchTimeout is the default timeout for downloading a single feed
+var DefaultFetchTimeout = 5 * time.Second
+
+// DefaultUserAgent is the default user agent used when downloading feeds
+var DefaultUserAgent = "goread (by /u/TypicalAM)"
+
+// DefaultFetchWorkers is the default amount of feeds fetched at the same time
+var DefaultFetchWorkers = 8
+
+// DefaultFetchWorkersPerHost is the default amount of feeds fetched at the same time from a single host
+var DefaultFetchWorkersPerHost = 2
 
 // SortableArticles is a sortable list of articles
 type SortableArticles []gofeed.Item
@@ -46,20 +64,55 @@ func (sa SortableArticles) Swap(a, b int) {
 	sa[a], sa[b] = sa[b], sa[a]
 }
 
+// Fetcher sends the http requests made by the cache, *http.Client satisfies it. It can be replaced
+// to route the requests through a custom transport or to serve canned responses in tests.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 // Cache handles the caching of feeds and storing downloaded articles
 type Cache struct {
-	Content     map[string]Entry `json:"content"`
-	filePath    string
-	Downloaded  SortableArticles `json:"downloaded"`
-	OfflineMode bool             `json:"-"`
+	Version         int               `json:"version"`
+	Fetcher         Fetcher           `json:"-"`
+	Content         map[string]Entry  `json:"content"`
+	Health          map[string]Health `json:"health"`
+	Extracted       map[string]string `json:"extracted"`
+	ExtractFailures map[string]Health `json:"extract_failures,omitempty"`
+	filePath        string
+	mu              sync.RWMutex
+	dirty           bool
+	Downloaded      SortableArticles `json:"downloaded"`
+	OfflineMode     bool             `json:"-"`
 }
 
-// Entry is a cache entry
+// Entry is a cache entry, it keeps the history of the feed's articles along with the time each
+// of them was last seen in the feed
 type Entry struct {
-	Expire   time.Time        `json:"expire"`
-	Articles SortableArticles `json:"articles"`
+	Expire       time.Time            `json:"expire"`
+	ETag         string               `json:"etag,omitempty"`
+	LastModified string               `json:"last_modified,omitempty"`
+	Hints        RefreshHints         `json:"hints"`
+	Articles     SortableArticles     `json:"articles"`
+	Seen         map[string]time.Time `json:"seen,omitempty"`
+	LastUsed     time.Time            `json:"last_used"`
 }
 
+// fetchResult is the outcome of fetching a feed from the internet
+type fetchResult struct {
+	articles     SortableArticles
+	header       http.Header
+	hints        RefreshHints
+	etag         string
+	lastModified string
+	notModified  bool
+}
+
+// customFeed is the key under which the url of the feed an article came from is kept in its custom elements
+const customFeed = "goread:feed"
+
+// errNotModified is returned when the server says that our cached copy of the feed is still valid
+var errNotModified = errors.New("not modified")
+
 // New creates a new cache store.
 func New(dir string) (*Cache, error) {
 	log.Println("Creating new cache store")
@@ -73,12 +126,21 @@ func New(dir string) (*Cache, error) {
 	}
 
 	return &Cache{
-		filePath:   filepath.Join(dir, "cache.json"),
-		Content:    make(map[string]Entry),
-		Downloaded: make(SortableArticles, 0),
+		Fetcher:         newClient(),
+		filePath:        filepath.Join(dir, "cache.json"),
+		Content:         make(map[string]Entry),
+		Health:          make(map[string]Health),
+		Extracted:       make(map[string]string),
+		ExtractFailures: make(map[string]Health),
+		Downloaded:      make(SortableArticles, 0),
 	}, nil
 }
 
+// Dir returns the directory the cache is stored in
+func (c *Cache) Dir() string {
+	return filepath.Dir(c.filePath)
+}
+
 // Load reads the cache from disk
 func (c *Cache) Load() error {
 	log.Println("Loading cache from", c.filePath)
@@ -91,134 +153,392 @@ func (c *Cache) Load() error {
 		return fmt.Errorf("cache.Load: %w", err)
 	}
 
-	if err = json.Unmarshal(data, &c); err != nil {
+	if data, err = cacheSchema.Upgrade(c.filePath, data); err != nil {
 		return fmt.Errorf("cache.Load: %w", err)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("cache.Load: %w", err)
+	}
+
+	if c.Health == nil {
+		c.Health = make(map[string]Health)
+	}
+
+	if c.Extracted == nil {
+		c.Extracted = make(map[string]string)
+	}
+
+	if c.ExtractFailures == nil {
+		c.ExtractFailures = make(map[string]Health)
+	}
+
 	log.Println("Loaded cache entries: ", len(c.Content))
 	return nil
 }
 
-// Save writes the cache to disk
+// Dirty reports if the cache changed since it was last saved
+func (c *Cache) Dirty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.dirty
+}
+
+// Save writes the cache to disk, the file is replaced atomically so a crash can't corrupt it
 func (c *Cache) Save() error {
-	// Iterate over the cache and remove any expired items
-	for key, value := range c.Content {
-		if value.Expire.Before(time.Now()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Purge the old articles from the history, an expired entry is only removed once it has no articles left
+	now := time.Now()
+	for key, entry := range c.Content {
+		entry.pruneHistory(now)
+		if len(entry.Articles) == 0 && entry.Expire.Before(now) && entry.ETag == "" && entry.LastModified == "" {
 			delete(c.Content, key)
+			continue
 		}
+
+		c.Content[key] = entry
 	}
 
-	cacheData, err := json.Marshal(c)
-	if err != nil {
-		return fmt.Errorf("cache.Save: %w", err)
+	c.evict()
+
+	// The extracted content is only useful as long as its article is still around
+	links := make(map[string]struct{})
+	for _, entry := range c.Content {
+		for i := range entry.Articles {
+			links[entry.Articles[i].Link] = struct{}{}
+		}
+	}
+
+	for i := range c.Downloaded {
+		links[c.Downloaded[i].Link] = struct{}{}
 	}
 
-	// Try to write the data to the file
-	if err = os.WriteFile(c.filePath, cacheData, 0600); err != nil {
-		if err = os.MkdirAll(filepath.Dir(c.filePath), 0755); err != nil {
-			return fmt.Errorf("cache.Save: %w", err)
+	for link := range c.Extracted {
+		if _, ok := links[link]; !ok {
+			delete(c.Extracted, link)
 		}
+	}
 
-		if err = os.WriteFile(c.filePath, cacheData, 0600); err != nil {
-			return fmt.Errorf("cache.Save: %w", err)
+	for link := range c.ExtractFailures {
+		if _, ok := links[link]; !ok {
+			delete(c.ExtractFailures, link)
 		}
 	}
 
+	c.Version = cacheVersion
+	cacheData, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("cache.Save: %w", err)
+	}
+
+	if err = atomicfile.WriteFile(c.filePath, cacheData, 0600); err != nil {
+		return fmt.Errorf("cache.Save: %w", err)
+	}
+
+	c.dirty = false
 	return nil
 }
 
-// GetArticles returns an article list using the cache if possible
+// GetArticles returns an article list using the cache if possible. Expired entries are revalidated
+// using the ETag and Last-Modified headers the server sent with the previous response. If the download
+// fails, the health of the feed is updated and the stale entry is kept until the failure backoff expires.
+// The fetched articles are merged into the history of the feed, so articles which drop off the feed
+// are kept until the retention limits purge them.
 func (c *Cache) GetArticles(feed *rss.Feed, ignoreCache bool) (SortableArticles, error) {
+	return c.getArticles(feed, ignoreCache, true)
+}
+
+// getArticles gets the articles of a feed, if touch is set the feed is marked as used. The background
+// refresh doesn't touch the feeds, so that it doesn't keep the unused feeds from being evicted.
+func (c *Cache) getArticles(feed *rss.Feed, ignoreCache, touch bool) (SortableArticles, error) {
 	log.Println("Getting articles for", feed.URL, " from cache: ", !ignoreCache)
 
-	// Delete entry if expired
-	if item, ok := c.Content[feed.URL]; ok && !ignoreCache {
-		if item.Expire.After(time.Now()) {
-			return item.Articles, nil
+	c.mu.RLock()
+	cached, ok := c.Content[feed.URL]
+	c.mu.RUnlock()
+
+	if ok && !ignoreCache && cached.Expire.After(time.Now()) {
+		if touch {
+			c.touch(feed.URL)
 		}
 
-		delete(c.Content, feed.URL)
+		return filterArticles(feed, cached.Articles), nil
 	}
 
 	if c.OfflineMode {
 		return nil, errors.New("offline mode")
 	}
 
-	articles, err := fetchArticles(feed.URL)
+	result, err := c.fetchArticles(feed, &cached)
 	if err != nil {
+		c.recordFailure(feed.URL, cached, err)
 		return nil, fmt.Errorf("cache.GetArticles: %w", err)
 	}
 
-	if len(feed.BlacklistWords) != 0 {
-		log.Println("Using keyword blacklist for feed", feed.Name, ":", feed.BlacklistWords)
-		remaining := make([]gofeed.Item, 0)
-		for i := range articles {
-			if !includesKeywords(&articles[i], feed.BlacklistWords) {
-				remaining = append(remaining, articles[i])
-			}
-		}
+	c.recordSuccess(feed.URL)
 
-		articles = remaining
-	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if len(feed.WhitelistWords) != 0 {
-		log.Println("Using keyword whitelist for feed", feed.Name, ":", feed.WhitelistWords)
-		remaining := make([]gofeed.Item, 0)
-		for i := range articles {
-			if includesKeywords(&articles[i], feed.WhitelistWords) {
-				remaining = append(remaining, articles[i])
-			}
-		}
+	// NOTE: The entry might have changed while we were downloading, so we merge into the latest version
+	entry := c.Content[feed.URL]
+	entry.Seen = copySeen(entry.Seen)
+	if result.notModified {
+		log.Println("Feed", feed.URL, "was not modified, reusing the cached articles")
+		entry.markSeen(now)
+	} else {
+		entry.mergeArticles(result.articles, now)
+	}
 
-		articles = remaining
+	entry.pruneHistory(now)
+	entry.Expire = entryExpiry(feed.FetchOptions, result.hints, result.header, now)
+	entry.ETag = result.etag
+	entry.LastModified = result.lastModified
+	entry.Hints = result.hints
+	if touch {
+		entry.LastUsed = now
 	}
 
-	c.Content[feed.URL] = Entry{time.Now().Add(DefaultCacheDuration), articles}
-	return articles, nil
+	c.Content[feed.URL] = entry
+	c.dirty = true
+
+	return filterArticles(feed, entry.Articles), nil
 }
 
-// GetArticlesBulk returns a sorted list of articles from all the given urls, ignoring any errors
+// GetArticlesBulk returns a sorted list of articles from all the given urls, ignoring any errors.
+// The feeds are fetched using a pool of DefaultFetchWorkers workers, with at most
+// DefaultFetchWorkersPerHost of them talking to the same host at once.
 func (c *Cache) GetArticlesBulk(feeds []*rss.Feed, ignoreCache bool) SortableArticles {
+	return c.getArticlesBulk(feeds, ignoreCache, true)
+}
+
+// getArticlesBulk gets the articles of all the feeds, touching them if asked to
+func (c *Cache) getArticlesBulk(feeds []*rss.Feed, ignoreCache, touch bool) SortableArticles {
+	results := make([]SortableArticles, len(feeds))
+	urls := make([]string, len(feeds))
+	for i := range feeds {
+		urls[i] = feeds[i].URL
+	}
+
+	fetchAll(urls, func(i int) {
+		results[i] = c.getArticlesOrStale(feeds[i], ignoreCache, touch)
+	})
+
 	var result SortableArticles
+	for i := range results {
+		result = append(result, results[i]...)
+	}
+
+	sort.Sort(result)
+	return result
+}
 
-	for i, feed := range feeds {
-		if items, err := c.GetArticles(feeds[i], ignoreCache); err == nil {
-			result = append(result, items...)
-		} else {
-			// NOTE: Let's say you have 50 feeds and 5 fail, we don't want to keep trying failed feeds
-			// so we just fill the cache with an empty item. That way load for bulk feeds is faster next time.
-			log.Println("Error getting articles for", feed.URL, err, "filling with empty item")
-			c.Content[feed.URL] = Entry{time.Now().Add(DefaultCacheDuration), SortableArticles{}}
+// RefreshExpired fetches the expired feeds and returns the amount of new articles per feed url. Feeds
+// which weren't cached before don't count as having new articles, since we have nothing to compare them to.
+func (c *Cache) RefreshExpired(feeds []*rss.Feed) map[string]int {
+	now := time.Now()
+	expired := make([]*rss.Feed, 0)
+	known := make(map[string]map[string]struct{})
+
+	c.mu.RLock()
+	for _, feed := range feeds {
+		entry, ok := c.Content[feed.URL]
+		if ok && entry.Expire.After(now) {
+			continue
+		}
+
+		expired = append(expired, feed)
+		if ok {
+			known[feed.URL] = articleKeys(entry.Articles)
+		}
+	}
+	c.mu.RUnlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	c.getArticlesBulk(expired, false, false)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make(map[string]int)
+	for _, feed := range expired {
+		keys, ok := known[feed.URL]
+		if !ok {
+			continue
+		}
+
+		for _, article := range filterArticles(feed, c.Content[feed.URL].Articles) {
+			if _, ok := keys[articleKey(&article)]; !ok {
+				result[feed.URL]++
+			}
 		}
 	}
 
 	return result
 }
 
-// GetDownloaded returns a list of downloaded items
+// getArticlesOrStale gets the articles of a single feed for GetArticlesBulk, falling back to the stale articles
+func (c *Cache) getArticlesOrStale(feed *rss.Feed, ignoreCache, touch bool) SortableArticles {
+	items, err := c.getArticles(feed, ignoreCache, touch)
+	if err == nil {
+		return items
+	}
+
+	// NOTE: GetArticles already backed off the failed feed, so we just show whatever we have from last time
+	log.Println("Error getting articles for", feed.URL, err, "using the stale articles")
+	c.mu.RLock()
+	stale := c.Content[feed.URL].Articles
+	c.mu.RUnlock()
+	return filterArticles(feed, stale)
+}
+
+// GetDownloaded returns a sorted list of downloaded items, the order matches the indices used by RemoveFromDownloaded
 func (c *Cache) GetDownloaded() SortableArticles {
-	return c.Downloaded
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sort.Sort(c.Downloaded)
+	result := make(SortableArticles, len(c.Downloaded))
+	copy(result, c.Downloaded)
+	return result
 }
 
 // AddToDownloaded adds an item to the downloaded list
 func (c *Cache) AddToDownloaded(item gofeed.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.Downloaded = append(c.Downloaded, item)
+	c.dirty = true
 }
 
-// RemoveFromDownloaded removes an item from the downloaded list
+// RemoveFromDownloaded removes an item from the downloaded list along with its offline copies
 func (c *Cache) RemoveFromDownloaded(index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if index < 0 || index >= len(c.Downloaded) {
 		return errors.New("index out of range")
 	}
 
+	removed := c.Downloaded[index]
 	c.Downloaded = append(c.Downloaded[:index], c.Downloaded[index+1:]...)
+	c.removeAssets(&removed)
+	c.dirty = true
 	return nil
 }
 
-// fetchArticles fetches articles from the internet and returns them
-func fetchArticles(url string) (SortableArticles, error) {
-	log.Println("Fetching articles from", url)
-	feed, err := parseFeed(url)
+// articleKey returns a key identifying an article, the guid is preferred since links tend to change
+func articleKey(article *gofeed.Item) string {
+	if article.GUID != "" {
+		return article.GUID
+	}
+
+	if article.Link != "" {
+		return article.Link
+	}
+
+	return article.Title
+}
+
+// articleFeed returns the url of the feed the article came from
+func articleFeed(article *gofeed.Item) string {
+	return article.Custom[customFeed]
+}
+
+// eachArticle calls fn for every cached and downloaded article
+func (c *Cache) eachArticle(fn func(item *gofeed.Item)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, entry := range c.Content {
+		for i := range entry.Articles {
+			fn(&entry.Articles[i])
+		}
+	}
+
+	for i := range c.Downloaded {
+		fn(&c.Downloaded[i])
+	}
+}
+
+// setArticleFeed remembers the url of the feed the article came from
+func setArticleFeed(article *gofeed.Item, url string) {
+	if article.Custom == nil {
+		article.Custom = make(map[string]string)
+	}
+
+	article.Custom[customFeed] = url
+}
+
+// copySeen copies the seen times of an entry, entries are stored by value so the map would otherwise be shared
+func copySeen(seen map[string]time.Time) map[string]time.Time {
+	result := make(map[string]time.Time, len(seen))
+	for key, value := range seen {
+		result[key] = value
+	}
+
+	return result
+}
+
+// articleKeys returns the set of keys of the articles
+func articleKeys(articles SortableArticles) map[string]struct{} {
+	keys := make(map[string]struct{}, len(articles))
+	for i := range articles {
+		keys[articleKey(&articles[i])] = struct{}{}
+	}
+
+	return keys
+}
+
+// fetchArticles fetches articles from the internet and returns them, the validators
+// of the cached entry are sent along to avoid downloading an unchanged feed. Transient
+// errors are retried with an exponential backoff, honoring the Retry-After header.
+func (c *Cache) fetchArticles(source *rss.Feed, cached *Entry) (*fetchResult, error) {
+	log.Println("Fetching articles from", source.URL)
+	feed, header, err := c.parseFeed(source.URL, source.FetchOptions, cached.ETag, cached.LastModified)
+	for attempt := 0; err != nil && !errors.Is(err, errNotModified); attempt++ {
+		wait, ok := retryDelay(err, attempt)
+		if !ok {
+			break
+		}
+
+		log.Println("Retrying", source.URL, "in", wait, "after error:", err)
+		time.Sleep(wait)
+		feed, header, err = c.parseFeed(source.URL, source.FetchOptions, cached.ETag, cached.LastModified)
+	}
+
+	if errors.Is(err, errNotModified) {
+		result := &fetchResult{
+			header:       header,
+			hints:        cached.Hints,
+			etag:         header.Get("ETag"),
+			lastModified: header.Get("Last-Modified"),
+			notModified:  true,
+		}
+
+		// Servers are allowed to omit the validators in a 304 response
+		if result.etag == "" {
+			result.etag = cached.ETag
+		}
+
+		if result.lastModified == "" {
+			result.lastModified = cached.LastModified
+		}
+
+		return result, nil
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("cache.fetchArticles: %w", err)
 	}
@@ -229,60 +549,158 @@ func fetchArticles(url string) (SortableArticles, error) {
 			continue
 		}
 
+		setArticleFeed(feed.Items[i], source.URL)
 		items = append(items, *feed.Items[i])
 	}
 
-	return items, nil
+	return &fetchResult{
+		articles:     items,
+		header:       header,
+		hints:        feedHints(feed),
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+	}, nil
 }
 
-// parseFeed parses a url and attempts to return a parsed feed
+// parseFeed reads a feed from its source and attempts to return a parsed feed along with the response headers,
+// errNotModified is returned if the server confirms that the feed didn't change since the validators were issued
 // authors note: this is was because the gofeed parser did not support reddit
-func parseFeed(url string) (*gofeed.Feed, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (c *Cache) parseFeed(url string, opts rss.FetchOptions, etag, lastModified string) (*gofeed.Feed, http.Header, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(opts))
+	defer cancel()
+
+	data, header, err := c.readFeed(ctx, url, opts, etag, lastModified)
+	if errors.Is(err, errNotModified) {
+		return nil, header, errNotModified
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("cache.parseFeed: %w", err)
+		return nil, nil, fmt.Errorf("cache.parseFeed: %w", err)
 	}
-	req.Header.Set("User-Agent", "goread (by /u/TypicalAM)")
 
-	client := http.Client{
+	feed, err := newParser().Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cache.parseFeed: %w", err)
+	}
+
+	if feed.PublishedParsed == nil && strings.TrimSpace(feed.Published) != "" {
+		// Fix for #64 since I don't want to bother gofeed's maintainer with date format additions
+		if t, err := time.Parse("Mon, 02 Jan 2006 15:04:05 GMT", strings.TrimSpace(feed.Published)); err == nil {
+			feed.PublishedParsed = &t
+		}
+	}
+
+	return feed, header, nil
+}
+
+// newRequest creates a GET request for the url which respects the fetch options
+func newRequest(ctx context.Context, url string, opts rss.FetchOptions) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// newClient creates the http client used for downloading feeds
+func newClient() *http.Client {
+	return &http.Client{
 		Transport: &http.Transport{
 			Proxy:        http.ProxyFromEnvironment,
 			TLSNextProto: map[string]func(authority string, c *tls.Conn) http.RoundTripper{},
 		},
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// fetcher returns the fetcher used for the requests, falling back to the default client
+func (c *Cache) fetcher() Fetcher {
+	if c.Fetcher == nil {
+		return newClient()
+	}
 
-	resp, err := client.Do(req.WithContext(ctx))
-	if err != nil {
-		return nil, fmt.Errorf("cache.parseFeed: %w", err)
+	return c.Fetcher
+}
+
+// fetchTimeout returns the timeout for a single download
+func fetchTimeout(opts rss.FetchOptions) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, gofeed.HTTPError{
-			StatusCode: resp.StatusCode,
-			Status:     resp.Status,
-		}
+	return DefaultFetchTimeout
+}
+
+// fetchAll calls fetch with the index of every url concurrently, with at most DefaultFetchWorkers calls running
+// at once and at most DefaultFetchWorkersPerHost of them for the same host. A call waits for a slot of its host
+// before it takes a worker, so that the urls of a busy host don't keep the workers from the other hosts.
+func fetchAll(urls []string, fetch func(i int)) {
+	limiter := newHostLimiter(DefaultFetchWorkersPerHost)
+	workers := DefaultFetchWorkers
+	if workers < 1 {
+		workers = 1
 	}
 
-	feed, err := gofeed.NewParser().Parse(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("cache.parseFeed: %w", err)
+	pool := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			release := limiter.acquire(urls[i])
+			defer release()
+
+			pool <- struct{}{}
+			defer func() { <-pool }()
+			fetch(i)
+		}(i)
 	}
 
-	if err := resp.Body.Close(); err != nil {
-		return nil, fmt.Errorf("cache.parseFeed: %w", err)
+	wg.Wait()
+}
+
+// hostLimiter limits the amount of concurrent requests made to a single host
+type hostLimiter struct {
+	slots   map[string]chan struct{}
+	mu      sync.Mutex
+	perHost int
+}
+
+// newHostLimiter creates a new hostLimiter allowing perHost concurrent requests to every host
+func newHostLimiter(perHost int) *hostLimiter {
+	if perHost < 1 {
+		perHost = 1
 	}
 
-	if feed.PublishedParsed == nil && strings.TrimSpace(feed.Published) != "" {
-		// Fix for #64 since I don't want to bother gofeed's maintainer with date format additions
-		if t, err := time.Parse("Mon, 02 Jan 2006 15:04:05 GMT", strings.TrimSpace(feed.Published)); err == nil {
-			feed.PublishedParsed = &t
-		}
+	return &hostLimiter{slots: make(map[string]chan struct{}), perHost: perHost}
+}
+
+// acquire blocks until there is a free slot for the host of the url, the returned function frees the slot
+func (hl *hostLimiter) acquire(rawURL string) func() {
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
 	}
 
-	return feed, nil
+	hl.mu.Lock()
+	slot, ok := hl.slots[host]
+	if !ok {
+		slot = make(chan struct{}, hl.perHost)
+		hl.slots[host] = slot
+	}
+	hl.mu.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
 }
 
 // getDefaultDir returns the default cache directory
@@ -295,16 +713,31 @@ func getDefaultDir() (string, error) {
 	return filepath.Join(dir, "goread"), nil
 }
 
-// includesKeywords checks if an article contains any specified keyword from a slice
-func includesKeywords(feed *gofeed.Item, keywords []string) bool {
-	for _, keyword := range keywords {
-		lowerKeyword := strings.ToLower(keyword)
-		if strings.Contains(strings.ToLower(feed.Title), lowerKeyword) ||
-			strings.Contains(strings.ToLower(feed.Description), lowerKeyword) ||
-			strings.Contains(strings.ToLower(feed.Content), lowerKeyword) {
-			return true
+// filterArticles applies the filter rules and the keyword lists of the feed to the articles. The cache keeps
+// the unfiltered articles so that changing the rules takes effect immediately, which is why
+// the result is always a new slice.
+func filterArticles(feed *rss.Feed, articles SortableArticles) SortableArticles {
+	scopes := feed.FilterScopes
+	if scopes == nil {
+		scopes = [][]string{feed.Filters}
+	}
+
+	rules, err := filter.NewScoped(scopes)
+	if err != nil {
+		log.Println("Skipping an invalid filter rule of feed", feed.Name, ":", err)
+	}
+
+	// NOTE: The keyword lists are a shorthand for matching any of the words in the title or the content,
+	// the whitelist is a scope of its own like the rules of the feed
+	rules.Add(filter.Keywords(filter.Hide, feed.BlacklistWords))
+	rules.Add(filter.Keywords(filter.Show, feed.WhitelistWords))
+
+	remaining := make(SortableArticles, 0, len(articles))
+	for i := range articles {
+		if rules.Allows(&articles[i]) {
+			remaining = append(remaining, articles[i])
 		}
 	}
 
-	return false
+	return remaining
 }
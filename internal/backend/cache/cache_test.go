@@ -1,12 +1,23 @@
 package cache
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/TypicalAM/goread/internal/backend/rss"
+	"github.com/TypicalAM/goread/internal/migrate"
+	"github.com/mmcdole/gofeed"
 )
 
 const TestOfflineDev = "TEST_OFFLINE_ONLY"
@@ -201,6 +212,54 @@ func TestCacheRespectBlacklist(t *testing.T) {
 	}
 }
 
+// TestCacheFilterRules if we get an error then the filter rules aren't applied to the articles
+func TestCacheFilterRules(t *testing.T) {
+	items := `<item><title>Go 1.22 released</title><guid>go</guid><category>golang</category></item>` +
+		`<item><title>Sponsored: buy this</title><guid>ad</guid><author>ads@example.com</author></item>` +
+		`<item><title>Rust 1.76 released</title><guid>rust</guid><category>rust</category></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	tests := []struct {
+		feed     rss.Feed
+		expected string
+	}{
+		{rss.Feed{}, "Go 1.22 released,Rust 1.76 released,Sponsored: buy this"},
+		{rss.Feed{Filters: []string{"hide author:ads@"}}, "Go 1.22 released,Rust 1.76 released"},
+		{rss.Feed{Filters: []string{"show tag:/^go(lang)?$/"}}, "Go 1.22 released"},
+		{rss.Feed{Filters: []string{"hide title:/^sponsored/ or tag:rust", "not a rule"}}, "Go 1.22 released"},
+		{rss.Feed{Filters: []string{"show released"}, BlacklistWords: []string{"rust"}}, "Go 1.22 released"},
+		{rss.Feed{Filters: []string{"show tag:rust"}, WhitelistWords: []string{"go"}}, ""},
+		{rss.Feed{Filters: []string{"show tag:rust"}, WhitelistWords: []string{"released"}}, "Rust 1.76 released"},
+		{rss.Feed{FilterScopes: [][]string{{"show released"}, {"show tag:rust"}}}, "Rust 1.76 released"},
+		{rss.Feed{FilterScopes: [][]string{nil, {"show tag:go", "show sponsored"}, {"show tag:/^go(lang)?$/"}}}, "Go 1.22 released"},
+	}
+
+	for _, test := range tests {
+		test.feed.URL = server.URL
+		articles, err := cache.GetArticles(&test.feed, false)
+		if err != nil {
+			t.Fatalf("couldn't get article: %v", err)
+		}
+
+		titles := make([]string, 0, len(articles))
+		for i := range articles {
+			titles = append(titles, articles[i].Title)
+		}
+
+		if strings.Join(titles, ",") != test.expected {
+			t.Errorf("expected %s for the filters %v, got %v", test.expected, test.feed.Filters, titles)
+		}
+	}
+}
+
 // TestCacheGetArticleExpired if we get an error then the store doesn't delete expired cache when getting data
 func TestCacheGetArticleExpired(t *testing.T) {
 	// This test should only run online
@@ -240,3 +299,1352 @@ func TestCacheGetArticleExpired(t *testing.T) {
 		t.Fatal("expected the data to be refreshed and the expire to be updated")
 	}
 }
+
+// newTestFeedServer creates a server which serves a small rss feed on every path
+func newTestFeedServer(handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler != nil {
+			handler(w, r)
+		}
+
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>%[1]s</title>
+<item><title>First %[1]s</title><link>http://example.com%[1]s/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>
+<item><title>Second %[1]s</title><link>http://example.com%[1]s/2</link><pubDate>Tue, 03 Jan 2006 15:04:05 GMT</pubDate></item>
+</channel></rss>`, r.URL.Path)
+	}))
+}
+
+// TestCacheGetArticlesBulk if we get an error then the bulk fetch doesn't respect the worker limits or the ordering
+func TestCacheGetArticlesBulk(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	server := newTestFeedServer(func(_ http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	defer server.Close()
+
+	oldWorkers, oldPerHost := DefaultFetchWorkers, DefaultFetchWorkersPerHost
+	DefaultFetchWorkers, DefaultFetchWorkersPerHost = 8, 2
+	defer func() { DefaultFetchWorkers, DefaultFetchWorkersPerHost = oldWorkers, oldPerHost }()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feeds := make([]*rss.Feed, 20)
+	for i := range feeds {
+		feeds[i] = &rss.Feed{URL: fmt.Sprintf("%s/feed%d", server.URL, i)}
+	}
+
+	articles := cache.GetArticlesBulk(feeds, false)
+	if len(articles) != 40 {
+		t.Fatalf("expected 40 articles, got %d", len(articles))
+	}
+
+	if len(cache.Content) != 20 {
+		t.Fatalf("expected 20 items in cache, got %d", len(cache.Content))
+	}
+
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent requests to one host, got %d", maxRunning)
+	}
+
+	for i := 0; i < 20; i++ {
+		if !strings.HasPrefix(articles[i].Title, "Second") {
+			t.Fatalf("expected the newer articles first, got %s at %d", articles[i].Title, i)
+		}
+	}
+}
+
+// TestCacheGetArticlesBulkBusyHost if we get an error then the feeds of a busy host keep the workers from the other hosts
+func TestCacheGetArticlesBulkBusyHost(t *testing.T) {
+	release := make(chan struct{})
+	slow := newTestFeedServer(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	defer slow.Close()
+
+	var mu sync.Mutex
+	fast := 0
+	other := newTestFeedServer(func(_ http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// NOTE: The slow host is unblocked once every feed of the other host was fetched
+		if fast++; fast == 3 {
+			close(release)
+		}
+	})
+	defer other.Close()
+
+	oldWorkers, oldPerHost := DefaultFetchWorkers, DefaultFetchWorkersPerHost
+	DefaultFetchWorkers, DefaultFetchWorkersPerHost = 4, 1
+	defer func() { DefaultFetchWorkers, DefaultFetchWorkersPerHost = oldWorkers, oldPerHost }()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	var feeds []*rss.Feed
+	for i := 0; i < 6; i++ {
+		feeds = append(feeds, &rss.Feed{URL: fmt.Sprintf("%s/slow%d", slow.URL, i)})
+	}
+
+	for i := 0; i < 3; i++ {
+		feeds = append(feeds, &rss.Feed{URL: fmt.Sprintf("%s/fast%d", other.URL, i)})
+	}
+
+	start := time.Now()
+	if articles := cache.GetArticlesBulk(feeds, false); len(articles) != 18 {
+		t.Fatalf("expected 18 articles, got %d", len(articles))
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the other host to be fetched while the slow one is busy, took %v", elapsed)
+	}
+}
+
+// TestCacheConditionalRequest if we get an error then the cache doesn't revalidate expired entries
+func TestCacheConditionalRequest(t *testing.T) {
+	var mu sync.Mutex
+	var gotETag, gotModified string
+	full := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		gotETag, gotModified = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+		if gotETag == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>
+<item><title>Only article</title><link>http://example.com/1</link></item>
+</channel></rss>`)
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feed := rss.Feed{URL: server.URL}
+	if _, err = cache.GetArticles(&feed, false); err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	entry := cache.Content[server.URL]
+	if entry.ETag != `"v1"` || entry.LastModified == "" {
+		t.Fatalf("expected the validators to be stored, got %q and %q", entry.ETag, entry.LastModified)
+	}
+
+	entry.Expire = time.Now().Add(-time.Hour)
+	cache.Content[server.URL] = entry
+
+	articles, err := cache.GetArticles(&feed, false)
+	if err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	if gotETag != `"v1"` || gotModified != entry.LastModified {
+		t.Errorf("expected the validators to be sent, got %q and %q", gotETag, gotModified)
+	}
+
+	if full != 1 {
+		t.Errorf("expected the feed to be downloaded once, got %d", full)
+	}
+
+	if len(articles) != 1 {
+		t.Fatalf("expected the cached article to be reused, got %d articles", len(articles))
+	}
+
+	if !cache.Content[server.URL].Expire.After(time.Now()) {
+		t.Errorf("expected the expiry to be renewed")
+	}
+}
+
+// TestCacheFetchOptions if we get an error then the per-feed fetch options are not used
+func TestCacheFetchOptions(t *testing.T) {
+	var mu sync.Mutex
+	var gotAgent, gotHeader string
+	release := make(chan struct{})
+	server := newTestFeedServer(func(_ http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotAgent, gotHeader = r.Header.Get("User-Agent"), r.Header.Get("X-Token")
+		mu.Unlock()
+
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+	})
+	defer server.Close()
+
+	// got returns the headers of the last request, the handlers run on the goroutines of the server
+	got := func() (string, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		return gotAgent, gotHeader
+	}
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feed := rss.Feed{URL: server.URL + "/fast", FetchOptions: rss.FetchOptions{
+		UserAgent: "Mozilla/5.0",
+		Headers:   map[string]string{"X-Token": "secret"},
+	}}
+
+	if _, err = cache.GetArticles(&feed, false); err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	if agent, header := got(); agent != "Mozilla/5.0" || header != "secret" {
+		t.Errorf("expected the custom user agent and header, got %q and %q", agent, header)
+	}
+
+	oldRetries := DefaultFetchRetries
+	DefaultFetchRetries = 0
+	defer func() { DefaultFetchRetries = oldRetries }()
+
+	feed = rss.Feed{URL: server.URL + "/slow", FetchOptions: rss.FetchOptions{Timeout: 50 * time.Millisecond}}
+	if _, err = cache.GetArticles(&feed, false); err == nil {
+		t.Errorf("expected the custom timeout to be exceeded")
+	}
+
+	// NOTE: The slow handler is still running, it has to finish before its headers are checked
+	close(release)
+	server.Close()
+
+	if agent, _ := got(); agent != DefaultUserAgent {
+		t.Errorf("expected the default user agent, got %q", agent)
+	}
+}
+
+// TestCacheRetry if we get an error then transient failures are not retried
+func TestCacheRetry(t *testing.T) {
+	attempts := 0
+	server := newTestFeedServer(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	defer server.Close()
+
+	oldBackoff := DefaultRetryBackoff
+	DefaultRetryBackoff = time.Millisecond
+	defer func() { DefaultRetryBackoff = oldBackoff }()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feed := rss.Feed{URL: server.URL + "/flaky"}
+	articles, err := cache.GetArticles(&feed, false)
+	if err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	if attempts != 3 || len(articles) != 2 {
+		t.Fatalf("expected 2 articles after 3 attempts, got %d after %d", len(articles), attempts)
+	}
+
+	if health, ok := cache.GetHealth(feed.URL); !ok || health.Failing() || health.LastSuccess.IsZero() {
+		t.Errorf("expected a healthy feed, got %+v", health)
+	}
+
+	if wait := parseRetryAfter("120", time.Now()); wait != 2*time.Minute {
+		t.Errorf("expected a two minute delay, got %v", wait)
+	}
+
+	now := time.Now()
+	if wait := parseRetryAfter(now.Add(time.Hour).UTC().Format(http.TimeFormat), now); wait < 59*time.Minute {
+		t.Errorf("expected a one hour delay, got %v", wait)
+	}
+}
+
+// TestCacheHealth if we get an error then failed feeds are not tracked or backed off
+func TestCacheHealth(t *testing.T) {
+	failing := false
+	server := newTestFeedServer(func(w http.ResponseWriter, _ *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feed := rss.Feed{URL: server.URL + "/feed"}
+	if _, err = cache.GetArticles(&feed, false); err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	failing = true
+	for i := 0; i < 2; i++ {
+		if _, err = cache.GetArticles(&feed, true); err == nil {
+			t.Fatalf("expected the download to fail")
+		}
+	}
+
+	health, _ := cache.GetHealth(feed.URL)
+	if health.ConsecutiveFailures != 2 || !strings.Contains(health.LastError, "404") {
+		t.Fatalf("expected two failures with a 404, got %+v", health)
+	}
+
+	entry := cache.Content[feed.URL]
+	if len(entry.Articles) != 2 {
+		t.Errorf("expected the stale articles to be kept, got %d", len(entry.Articles))
+	}
+
+	if backoff := time.Until(entry.Expire); backoff > 2*DefaultFailureBackoff || backoff < DefaultFailureBackoff {
+		t.Errorf("expected the feed to be backed off for twice the failure backoff, got %v", backoff)
+	}
+
+	if articles := cache.GetArticlesBulk([]*rss.Feed{&feed}, true); len(articles) != 2 {
+		t.Errorf("expected the stale articles in bulk, got %d", len(articles))
+	}
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache %v", err)
+	}
+
+	loaded, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	if err = loaded.Load(); err != nil {
+		t.Fatalf("couldn't load the cache %v", err)
+	}
+
+	if health, _ = loaded.GetHealth(feed.URL); health.ConsecutiveFailures != 3 {
+		t.Errorf("expected the health to be persisted, got %+v", health)
+	}
+}
+
+// TestCacheRefreshHints if we get an error then the feed refresh hints are not respected
+func TestCacheRefreshHints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ttl":
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>TTL</title><ttl>90</ttl>
+<item><title>Only article</title></item></channel></rss>`)
+
+		case "/sy":
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+<channel><title>Sy</title><sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency>4</sy:updateFrequency>
+<item><title>Only article</title></item></channel></rss>`)
+
+		case "/header":
+			w.Header().Set("Cache-Control", "public, max-age=7200")
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Header</title><ttl>1</ttl>
+<item><title>Only article</title></item></channel></rss>`)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"/ttl":    90 * time.Minute,
+		"/sy":     6 * time.Hour,
+		"/header": 2 * time.Hour,
+	}
+
+	for path, interval := range expected {
+		feed := rss.Feed{URL: server.URL + path}
+		if _, err = cache.GetArticles(&feed, false); err != nil {
+			t.Fatalf("couldn't get article: %v", err)
+		}
+
+		if got := time.Until(cache.Content[feed.URL].Expire); got > interval || got < interval-time.Minute {
+			t.Errorf("expected %s to expire in %v, got %v", path, interval, got)
+		}
+	}
+
+	feed := rss.Feed{URL: server.URL + "/ttl", FetchOptions: rss.FetchOptions{RefreshInterval: 10 * time.Minute}}
+	if _, err = cache.GetArticles(&feed, true); err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	if got := time.Until(cache.Content[feed.URL].Expire); got > 10*time.Minute || got < 9*time.Minute {
+		t.Errorf("expected the refresh interval to win, got %v", got)
+	}
+
+	now := time.Date(2024, time.January, 6, 22, 30, 0, 0, time.UTC) // Saturday
+	hints := RefreshHints{SkipHours: []int{23}, SkipDays: []time.Weekday{time.Sunday}}
+	if got := skipUntil(hints, now.Add(time.Hour)); !got.Equal(time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the skipped hours and days to be skipped, got %v", got)
+	}
+}
+
+// TestCacheRefreshExpired if we get an error then the background refresh doesn't count the new articles
+func TestCacheRefreshExpired(t *testing.T) {
+	items := `<item><title>Old</title><guid>old</guid></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	fresh := rss.Feed{URL: server.URL + "/fresh"}
+	stale := rss.Feed{URL: server.URL + "/stale"}
+	unknown := rss.Feed{URL: server.URL + "/unknown"}
+	for _, feed := range []*rss.Feed{&fresh, &stale} {
+		if _, err = cache.GetArticles(feed, false); err != nil {
+			t.Fatalf("couldn't get article: %v", err)
+		}
+	}
+
+	entry := cache.Content[stale.URL]
+	entry.Expire = time.Now().Add(-time.Minute)
+	cache.Content[stale.URL] = entry
+
+	items += `<item><title>New</title><guid>new</guid></item><item><title>Newer</title><guid>newer</guid></item>`
+	newArticles := cache.RefreshExpired([]*rss.Feed{&fresh, &stale, &unknown})
+	if len(newArticles) != 1 || newArticles[stale.URL] != 2 {
+		t.Errorf("expected 2 new articles in the stale feed only, got %v", newArticles)
+	}
+
+	if len(cache.Content[fresh.URL].Articles) != 1 {
+		t.Errorf("expected the fresh feed not to be refreshed")
+	}
+
+	if _, ok := cache.Content[unknown.URL]; !ok {
+		t.Errorf("expected the uncached feed to be fetched")
+	}
+}
+
+// TestCacheHistory if we get an error then the fetched articles aren't merged into the history or the retention is broken
+func TestCacheHistory(t *testing.T) {
+	items := `<item><title>Old</title><guid>old</guid></item><item><title>Changing</title><guid>changing</guid></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>%s</channel></rss>`, items)
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feed := rss.Feed{URL: server.URL}
+	if _, err = cache.GetArticles(&feed, false); err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	items = `<item><title>Changed</title><guid>changing</guid></item><item><title>New</title><guid>new</guid></item>`
+	articles, err := cache.GetArticles(&feed, true)
+	if err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	titles := make([]string, 0, len(articles))
+	for i := range articles {
+		titles = append(titles, articles[i].Title)
+	}
+
+	if strings.Join(titles, ",") != "Changed,New,Old" {
+		t.Errorf("expected the old article to be kept and the changed one to be updated, got %v", titles)
+	}
+
+	// The article which dropped off the feed is purged once it is too old
+	oldAge := DefaultHistoryAge
+	defer func() { DefaultHistoryAge = oldAge }()
+	DefaultHistoryAge = time.Hour
+
+	entry := cache.Content[feed.URL]
+	entry.Seen["old"] = time.Now().Add(-2 * time.Hour)
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	if articles = cache.Content[feed.URL].Articles; len(articles) != 2 {
+		t.Errorf("expected the old article to be purged, got %d articles", len(articles))
+	}
+
+	// Only the newest articles are kept when there are too many of them
+	oldSize := DefaultHistorySize
+	defer func() { DefaultHistorySize = oldSize }()
+	DefaultHistorySize = 1
+
+	items = `<item><title>Newest</title><guid>newest</guid></item>`
+	if articles, _ = cache.GetArticles(&feed, true); len(articles) != 1 || articles[0].Title != "Newest" {
+		t.Errorf("expected only the newest article to be kept, got %v", articles)
+	}
+
+	// Expired entries are kept in the cache as long as they have articles
+	entry = cache.Content[feed.URL]
+	entry.Expire = time.Now().Add(-time.Hour)
+	cache.Content[feed.URL] = entry
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	if _, ok := cache.Content[feed.URL]; !ok {
+		t.Errorf("expected the expired entry to be kept")
+	}
+}
+
+// TestCacheEvict if we get an error then the least recently used feeds aren't evicted or the downloaded articles are
+func TestCacheEvict(t *testing.T) {
+	server := newTestFeedServer(nil)
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	feeds := []*rss.Feed{{URL: server.URL + "/a"}, {URL: server.URL + "/b"}, {URL: server.URL + "/c"}}
+	for _, feed := range feeds {
+		if _, err = cache.GetArticles(feed, false); err != nil {
+			t.Fatalf("couldn't get article: %v", err)
+		}
+
+		cache.AddToDownloaded(cache.Content[feed.URL].Articles[0])
+	}
+
+	// Using the first feed makes the second one the least recently used
+	time.Sleep(time.Millisecond)
+	if _, err = cache.GetArticles(feeds[0], false); err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	// An entry without articles only keeps the validators, it is never the one evicted
+	cache.Content[server.URL+"/empty"] = Entry{ETag: `"empty"`}
+
+	// The background refresh doesn't count as using a feed
+	entry := cache.Content[feeds[1].URL]
+	entry.Expire = time.Now().Add(-time.Minute)
+	cache.Content[feeds[1].URL] = entry
+	cache.RefreshExpired(feeds)
+
+	oldSize := DefaultCacheSize
+	defer func() { DefaultCacheSize = oldSize }()
+	DefaultCacheSize = 3
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	if _, ok := cache.Content[feeds[1].URL]; ok {
+		t.Errorf("expected the least recently used feed to be evicted")
+	}
+
+	if articles := cache.Content[feeds[2].URL].Articles; len(articles) != 1 || articles[0].Title != "Second /c" {
+		t.Errorf("expected only the newest article of the next feed to be kept, got %v", articles)
+	}
+
+	if len(cache.Content[feeds[0].URL].Articles) != 2 {
+		t.Errorf("expected the most recently used feed to be kept")
+	}
+
+	if _, ok := cache.Content[server.URL+"/empty"]; !ok {
+		t.Errorf("expected the entry without articles to be kept")
+	}
+
+	if len(cache.GetDownloaded()) != 3 {
+		t.Errorf("expected the downloaded articles to be kept, got %d", len(cache.GetDownloaded()))
+	}
+}
+
+// TestCacheDirty if we get an error then the changes aren't tracked or the save doesn't reset them
+func TestCacheDirty(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	readStatus, err := NewReadStatus(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the read status %v", err)
+	}
+
+	if cache.Dirty() || readStatus.Dirty() {
+		t.Errorf("expected a new cache and read status to be clean")
+	}
+
+	item := gofeed.Item{Title: "Saved", Link: "https://example.com/saved"}
+	cache.AddToDownloaded(item)
+	readStatus.MarkAsRead(NewArticleID(&item))
+	if !cache.Dirty() || !readStatus.Dirty() {
+		t.Errorf("expected the changes to make the cache and read status dirty")
+	}
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	if err = readStatus.Save(); err != nil {
+		t.Fatalf("couldn't save the read status: %v", err)
+	}
+
+	if cache.Dirty() || readStatus.Dirty() {
+		t.Errorf("expected the save to clean the cache and read status")
+	}
+
+	// Reading a feed only updates the time it was last used
+	cache.Content["https://example.com/feed.xml"] = Entry{Expire: time.Now().Add(time.Hour)}
+	cache.touch("https://example.com/feed.xml")
+	if cache.Dirty() || cache.Content["https://example.com/feed.xml"].LastUsed.IsZero() {
+		t.Errorf("expected reading a feed to be tracked without making the cache dirty")
+	}
+
+	loaded, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	if err = loaded.Load(); err != nil {
+		t.Fatalf("couldn't load the cache: %v", err)
+	}
+
+	if len(loaded.GetDownloaded()) != 1 {
+		t.Errorf("expected the saved article to be written to disk")
+	}
+}
+
+// TestCacheAnnotate if we get an error then the tags and the note of a saved article aren't kept
+func TestCacheAnnotate(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	other := gofeed.Item{Title: "Other", Link: "https://example.com/other"}
+	saved := gofeed.Item{Title: "Saved", Link: "https://example.com/saved"}
+	setArticleFeed(&saved, "https://example.com/feed.xml")
+	cache.AddToDownloaded(other)
+	cache.AddToDownloaded(saved)
+
+	unsaved := NewArticleID(&gofeed.Item{Title: "Unsaved", Link: "https://example.com/unsaved"})
+	if err = cache.Annotate(unsaved, []string{"research"}, ""); err == nil {
+		t.Errorf("expected an error for an article which isn't saved")
+	}
+
+	if err = cache.Annotate(NewArticleID(&saved), ParseTags(" #Research, go,, research "), "  read later  "); err != nil {
+		t.Fatalf("couldn't annotate the article: %v", err)
+	}
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	loaded, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	if err = loaded.Load(); err != nil {
+		t.Fatalf("couldn't load the cache: %v", err)
+	}
+
+	if untouched := loaded.GetDownloaded()[0]; len(Tags(&untouched)) != 0 || Note(&untouched) != "" {
+		t.Errorf("expected the other saved article to stay untouched, got %v", untouched.Custom)
+	}
+
+	saved = loaded.GetDownloaded()[1]
+	if tags := Tags(&saved); !reflect.DeepEqual(tags, []string{"Research", "go"}) {
+		t.Errorf("expected the tags to be kept, got %v", tags)
+	}
+
+	if note := Note(&saved); note != "read later" {
+		t.Errorf("expected the note to be kept, got %q", note)
+	}
+
+	if !HasTag(Tags(&saved), "#research") || HasTag(Tags(&saved), "rust") || HasTag(Tags(&saved), "#res") {
+		t.Errorf("expected the tags to be matched case-insensitively")
+	}
+
+	if err = loaded.Annotate(NewArticleID(&saved), nil, ""); err != nil {
+		t.Fatalf("couldn't annotate the article: %v", err)
+	}
+
+	saved = loaded.GetDownloaded()[1]
+	if len(Tags(&saved)) != 0 || Note(&saved) != "" {
+		t.Errorf("expected the tags and the note to be removed, got %v", saved.Custom)
+	}
+}
+
+// TestCacheReadStatus if we get an error then articles sharing a link collide, old entries aren't pruned or the old format isn't converted
+func TestCacheReadStatus(t *testing.T) {
+	dir := t.TempDir()
+	first := gofeed.Item{Title: "First", Link: "https://example.com/shared"}
+	second := gofeed.Item{Title: "Second", Link: "https://example.com/shared"}
+	setArticleFeed(&first, "https://example.com/first.xml")
+	setArticleFeed(&second, "https://example.com/second.xml")
+	migrated := gofeed.Item{Title: "Migrated", Link: "https://example.com/migrated"}
+	uncached := gofeed.Item{Title: "Uncached", Link: "https://example.com/uncached"}
+	setArticleFeed(&migrated, "https://example.com/first.xml")
+	setArticleFeed(&uncached, "https://example.com/first.xml")
+
+	// The old format is a bare set of little endian murmur3 hashes of the links
+	legacy := make([]byte, 8)
+	binary.LittleEndian.PutUint32(legacy, hashArticle(migrated.Link))
+	binary.LittleEndian.PutUint32(legacy[4:], hashArticle(uncached.Link))
+	if err := os.WriteFile(filepath.Join(dir, "read_status"), legacy, 0600); err != nil {
+		t.Fatalf("couldn't write the old read status: %v", err)
+	}
+
+	cache, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	cache.Content["https://example.com/first.xml"] = Entry{Articles: SortableArticles{migrated, first}}
+	readStatus, err := NewReadStatus(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the read status %v", err)
+	}
+
+	if err = readStatus.Load(); err != nil {
+		t.Fatalf("couldn't load the read status: %v", err)
+	}
+
+	if converted := readStatus.ConvertLegacy(cache); converted != 1 {
+		t.Errorf("expected only the cached article to be converted, got %d", converted)
+	}
+
+	if !readStatus.IsRead(NewArticleID(&migrated)) {
+		t.Errorf("expected the migrated article to be read")
+	}
+
+	// The hashes of the articles which aren't cached are dropped instead of being checked forever
+	if readStatus.IsRead(NewArticleID(&uncached)) || len(readStatus.legacy) != 0 {
+		t.Errorf("expected the unmatched hashes to be dropped")
+	}
+
+	readStatus.MarkAsRead(NewArticleID(&first))
+	if !readStatus.IsRead(NewArticleID(&first)) || readStatus.IsRead(NewArticleID(&second)) {
+		t.Errorf("expected only the article from the first feed to be read")
+	}
+
+	if err = readStatus.Save(); err != nil {
+		t.Fatalf("couldn't save the read status: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "read_status")); !os.IsNotExist(err) {
+		t.Errorf("expected the old read status file to be moved aside, got %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "read_status.v1.bak")); err != nil {
+		t.Errorf("expected the old read status file to be backed up, got %v", err)
+	}
+
+	loaded, err := NewReadStatus(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the read status %v", err)
+	}
+
+	if err = loaded.Load(); err != nil {
+		t.Fatalf("couldn't load the read status: %v", err)
+	}
+
+	if !loaded.IsRead(NewArticleID(&first)) || !loaded.IsRead(NewArticleID(&migrated)) {
+		t.Errorf("expected the read status to survive a save")
+	}
+
+	// Entries older than the configured age are forgotten
+	oldAge := DefaultReadStatusAge
+	defer func() { DefaultReadStatusAge = oldAge }()
+	DefaultReadStatusAge = time.Hour
+
+	loaded.read[articleFeed(&first)][articleKey(&first)] = time.Now().Add(-2 * time.Hour)
+	if err = loaded.Save(); err != nil {
+		t.Fatalf("couldn't save the read status: %v", err)
+	}
+
+	if loaded.IsRead(NewArticleID(&first)) || !loaded.IsRead(NewArticleID(&migrated)) {
+		t.Errorf("expected only the old entries to be pruned")
+	}
+}
+
+// TestCacheSaveOffline if we get an error then the images of a saved article aren't downloaded or cleaned up
+func TestCacheSaveOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "not really a png")
+
+		case "/post":
+			fmt.Fprint(w, `<html><body><article><p>The whole article is a lot longer than the teaser.</p>
+<img src="/image"></article></body></html>`)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	oldSavePages := DefaultSavePages
+	defer func() { DefaultSavePages = oldSavePages }()
+	DefaultSavePages = true
+
+	item := gofeed.Item{
+		Title:   "Saved",
+		Link:    server.URL + "/post",
+		Content: `<p>Teaser</p><img src="/image" srcset="/image 2x"><img src="/missing">`,
+	}
+
+	cache.SaveOffline(&item, rss.FetchOptions{})
+	if strings.Count(item.Content, "file://") != 1 || !strings.Contains(item.Content, server.URL+"/missing") {
+		t.Errorf("expected only the available image to point at a local copy, got %s", item.Content)
+	}
+
+	if strings.Contains(item.Content, "srcset") {
+		t.Errorf("expected the remote srcset to be dropped, got %s", item.Content)
+	}
+
+	if extracted, ok := cache.GetExtracted(item.Link); !ok || !strings.Contains(extracted, "file://") {
+		t.Errorf("expected the article page to be saved with local images, got %q", extracted)
+	}
+
+	dir := filepath.Join(cache.Dir(), "assets", item.Custom[customAssets])
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 || filepath.Ext(entries[0].Name()) != ".png" {
+		t.Fatalf("expected a single png in the asset directory, got %v %v", entries, err)
+	}
+
+	cache.AddToDownloaded(item)
+	if err = cache.RemoveFromDownloaded(0); err != nil {
+		t.Fatalf("couldn't remove the article: %v", err)
+	}
+
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the assets to be removed with the article, got %v", err)
+	}
+}
+
+// TestCacheExtract if we get an error then the full article content is not extracted or cached
+func TestCacheExtract(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		fmt.Fprint(w, `<html><body>
+<nav><a href="/">Home</a><a href="/about">About us</a></nav>
+<div class="ad-banner">Buy our stuff</div>
+<div class="layout">
+	<div id="sidebar-widget"><p>Subscribe to the newsletter for more of this</p></div>
+	<div class="post-content">
+		<p>The first paragraph of the article is long enough to be the content.</p>
+		<p>The second paragraph has <a href="/more">a relative link</a>.</p>
+	</div>
+</div>
+<footer>Copyright</footer>
+</body></html>`)
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	link := server.URL + "/article"
+	content, err := cache.GetFullContent(link, rss.FetchOptions{})
+	if err != nil {
+		t.Fatalf("couldn't extract the article: %v", err)
+	}
+
+	if !strings.Contains(content, "first paragraph") || !strings.Contains(content, server.URL+"/more") {
+		t.Errorf("expected the article content with absolute links, got %q", content)
+	}
+
+	for _, clutter := range []string{"About us", "Buy our stuff", "newsletter", "Copyright"} {
+		if strings.Contains(content, clutter) {
+			t.Errorf("expected %q to be stripped, got %q", clutter, content)
+		}
+	}
+
+	cache.OfflineMode = true
+	if cached, err := cache.GetFullContent(link, rss.FetchOptions{}); err != nil || cached != content || requests != 1 {
+		t.Errorf("expected the cached content in offline mode, got %v after %d requests", err, requests)
+	}
+
+	if _, err = cache.GetFullContent(server.URL+"/other", rss.FetchOptions{}); err == nil {
+		t.Errorf("expected uncached articles to fail in offline mode")
+	}
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache %v", err)
+	}
+
+	if _, ok := cache.GetExtracted(link); ok {
+		t.Errorf("expected the content of unknown articles to be pruned")
+	}
+}
+
+// TestCacheExtractNew if we get an error then the old articles are extracted or the failed ones are retried right away
+func TestCacheExtractNew(t *testing.T) {
+	page := `<html><body><article><p>The full content of the article.</p></article></body></html>`
+	fetcher := &fakeFetcher{feeds: map[string]string{
+		"https://example.com/feed": `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>
+<item><title>Old</title><link>https://example.com/old</link></item></channel></rss>`,
+	}}
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	cache.Fetcher = fetcher
+	feed := rss.Feed{URL: "https://example.com/feed", FullContent: true}
+	if _, err = cache.GetArticles(&feed, false); err != nil {
+		t.Fatalf("couldn't get the articles: %v", err)
+	}
+
+	// The old article drops off the feed before it is ever extracted
+	fetcher.feeds["https://example.com/feed"] = `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>
+<item><title>New</title><link>https://example.com/new</link></item>
+<item><title>Broken</title><link>https://example.com/broken</link></item></channel></rss>`
+	fetcher.feeds["https://example.com/new"] = page
+	fetcher.feeds["https://example.com/old"] = page
+	time.Sleep(time.Millisecond)
+	if _, err = cache.GetArticles(&feed, true); err != nil {
+		t.Fatalf("couldn't get the articles: %v", err)
+	}
+
+	fetcher.requests = 0
+	if extracted := cache.ExtractNew(&feed); extracted != 1 || fetcher.requests != 2 {
+		t.Errorf("expected only the new article to be extracted, got %d from %d requests", extracted, fetcher.requests)
+	}
+
+	if _, ok := cache.GetExtracted("https://example.com/old"); ok {
+		t.Errorf("expected the article from the history to be left alone")
+	}
+
+	if extracted := cache.ExtractNew(&feed); extracted != 0 || fetcher.requests != 2 {
+		t.Errorf("expected the failed article to back off, got %d from %d requests", extracted, fetcher.requests)
+	}
+
+	failed := cache.ExtractFailures["https://example.com/broken"]
+	failed.LastFailure = time.Now().Add(-DefaultFailureBackoff)
+	cache.ExtractFailures["https://example.com/broken"] = failed
+	if cache.ExtractNew(&feed); fetcher.requests != 3 || cache.ExtractFailures["https://example.com/broken"].ConsecutiveFailures != 2 {
+		t.Errorf("expected the failed article to be retried after the backoff, got %d requests", fetcher.requests)
+	}
+}
+
+// TestCacheSources if we get an error then the local file, command and filter sources don't work
+func TestCacheSources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands in this test need a posix shell")
+	}
+
+	server := newTestFeedServer(nil)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "feed.xml")
+	feed := `<?xml version="1.0"?><rss version="2.0"><channel><title>Local</title>
+<item><title>Local article</title><link>http://example.com/local</link></item></channel></rss>`
+	if err := os.WriteFile(path, []byte(feed), 0600); err != nil {
+		t.Fatalf("couldn't write the feed file %v", err)
+	}
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	expected := map[string]string{
+		"file://" + path:   "Local article",
+		"exec:cat " + path: "Local article",
+		"filter:" + server.URL + "/feed|sed s/First/Third/": "Third /feed",
+		"filter:file://" + path + "|sed s/Local/Filtered/":  "Filtered article",
+	}
+
+	for url, title := range expected {
+		articles, err := cache.GetArticles(&rss.Feed{URL: url}, false)
+		if err != nil {
+			t.Errorf("couldn't get the articles from %s: %v", url, err)
+			continue
+		}
+
+		found := false
+		for _, article := range articles {
+			found = found || article.Title == title
+		}
+
+		if !found {
+			t.Errorf("expected %q in the articles from %s, got %v", title, url, articles)
+		}
+	}
+
+	_, err = cache.GetArticles(&rss.Feed{URL: "exec:echo broken generator >&2; exit 3"}, false)
+	if err == nil || !strings.Contains(unwrapAll(err).Error(), "broken generator") {
+		t.Errorf("expected the command output in the error, got %v", err)
+	}
+
+	if _, err = cache.GetArticles(&rss.Feed{URL: "filter:" + server.URL + "/feed"}, false); err == nil {
+		t.Errorf("expected a filter without a command to fail")
+	}
+
+	candidates, err := cache.Discover("exec:cat " + path)
+	if err != nil || len(candidates) != 1 || candidates[0].URL != "exec:cat "+path {
+		t.Errorf("expected the command to be its own candidate, got %v and %v", candidates, err)
+	}
+}
+
+// unwrapAll returns the innermost error, just like the browser does when it shows an error
+func unwrapAll(err error) error {
+	for errors.Unwrap(err) != nil {
+		err = errors.Unwrap(err)
+	}
+
+	return err
+}
+
+// TestCacheDiscover if we get an error then feed autodiscovery doesn't work
+func TestCacheDiscover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head>
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+<link rel="alternate" type="application/atom+xml" href="atom">
+<link rel="alternate" type="text/html" href="/other">
+</head><body></body></html>`)
+
+		case "/plain":
+			fmt.Fprint(w, `<html><head><title>No feeds here</title></head></html>`)
+
+		case "/feed.xml", "/atom", "/feed":
+			fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Feed %s</title></channel></rss>`, r.URL.Path)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	candidates, err := cache.Discover(server.URL + "/")
+	if err != nil {
+		t.Fatalf("couldn't discover feeds: %v", err)
+	}
+
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(candidates))
+	}
+
+	if candidates[0].URL != server.URL+"/feed.xml" || candidates[0].Title != "Feed /feed.xml" {
+		t.Errorf("expected the advertised feed, got %+v", candidates[0])
+	}
+
+	candidates, err = cache.Discover(server.URL + "/plain")
+	if err != nil {
+		t.Fatalf("couldn't discover feeds: %v", err)
+	}
+
+	if len(candidates) != 1 || candidates[0].URL != server.URL+"/feed" {
+		t.Errorf("expected the probed feed, got %+v", candidates)
+	}
+
+	if candidates[0].Name("") != "Feed /feed" || candidates[0].Name("Mine") != "Mine" {
+		t.Errorf("expected the name to be prefilled from the title, got %s", candidates[0].Name(""))
+	}
+
+	candidates, err = cache.Discover(server.URL + "/atom")
+	if err != nil || len(candidates) != 1 || candidates[0].URL != server.URL+"/atom" {
+		t.Errorf("expected the feed url to be the only candidate, got %+v, %v", candidates, err)
+	}
+}
+
+// fakeFetcher serves canned feeds without touching the network
+type fakeFetcher struct {
+	feeds    map[string]string
+	requests int
+}
+
+// Do fulfills the Fetcher interface
+func (f *fakeFetcher) Do(req *http.Request) (*http.Response, error) {
+	f.requests++
+	recorder := httptest.NewRecorder()
+	if feed, ok := f.feeds[req.URL.String()]; ok {
+		fmt.Fprint(recorder, feed)
+	} else {
+		recorder.WriteHeader(http.StatusNotFound)
+	}
+
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// bareFetcher is a fetcher which doesn't set the request of its responses, like a minimal custom Fetcher
+type bareFetcher struct{ *fakeFetcher }
+
+// Do fulfills the Fetcher interface
+func (f bareFetcher) Do(req *http.Request) (*http.Response, error) {
+	resp, err := f.fakeFetcher.Do(req)
+	if resp != nil {
+		resp.Request = nil
+	}
+
+	return resp, err
+}
+
+// TestCacheBareFetcher if we get an error then a response without its request crashes the discovery or the extraction
+func TestCacheBareFetcher(t *testing.T) {
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	cache.Fetcher = bareFetcher{&fakeFetcher{feeds: map[string]string{
+		"https://example.com/":         `<html><head><link rel="alternate" type="application/rss+xml" href="/feed.xml"></head></html>`,
+		"https://example.com/feed.xml": `<?xml version="1.0"?><rss version="2.0"><channel><title>Feed</title></channel></rss>`,
+		"https://example.com/article":  `<html><body><article><p>Read <a href="/more">more</a>.</p></article></body></html>`,
+	}}}
+
+	candidates, err := cache.Discover("https://example.com/")
+	if err != nil || len(candidates) != 1 || candidates[0].URL != "https://example.com/feed.xml" {
+		t.Errorf("expected the advertised feed, got %+v (%v)", candidates, err)
+	}
+
+	content, err := cache.Extract("https://example.com/article", rss.FetchOptions{})
+	if err != nil || !strings.Contains(content, "https://example.com/more") {
+		t.Errorf("expected the links to be resolved against the request url, got %q (%v)", content, err)
+	}
+}
+
+// TestCacheFetcher if we get an error then the cache doesn't use the injected fetcher
+func TestCacheFetcher(t *testing.T) {
+	fetcher := &fakeFetcher{feeds: map[string]string{
+		"https://example.com/feed": `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>
+<item><title>Go is great</title><guid>1</guid></item><item><title>Rust is great</title><guid>2</guid></item>
+</channel></rss>`,
+	}}
+
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	cache.Fetcher = fetcher
+
+	feed := rss.Feed{URL: "https://example.com/feed", WhitelistWords: []string{"go"}}
+	articles, err := cache.GetArticles(&feed, false)
+	if err != nil {
+		t.Fatalf("couldn't get article: %v", err)
+	}
+
+	if len(articles) != 1 || articles[0].Title != "Go is great" || fetcher.requests != 1 {
+		t.Errorf("expected 1 filtered article from 1 request, got %d articles from %d requests", len(articles), fetcher.requests)
+	}
+
+	feed.WhitelistWords = nil
+	if articles, _ = cache.GetArticles(&feed, true); len(articles) != 2 || fetcher.requests != 2 {
+		t.Errorf("expected 2 refreshed articles from 2 requests, got %d articles from %d requests", len(articles), fetcher.requests)
+	}
+
+	cache.OfflineMode = true
+	if articles, _ = cache.GetArticles(&feed, false); len(articles) != 2 || fetcher.requests != 2 {
+		t.Errorf("expected the cached articles without a request in offline mode, got %d articles from %d requests", len(articles), fetcher.requests)
+	}
+
+	if _, err = cache.GetArticles(&feed, true); err == nil || fetcher.requests != 2 {
+		t.Errorf("expected a refresh to fail without a request in offline mode, got %v from %d requests", err, fetcher.requests)
+	}
+
+	cache.OfflineMode = false
+	if _, err = cache.GetArticles(&rss.Feed{URL: "https://example.com/missing"}, false); err == nil {
+		t.Errorf("expected an error for a missing feed")
+	}
+}
+
+// TestCacheMaintenance if we get an error then pruning, clearing or verifying the cache doesn't work
+func TestCacheMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	future, past := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+	articles := SortableArticles{{Title: "Article", Link: "https://example.com/article"}}
+	cache.Content["https://example.com/kept"] = Entry{Expire: future, Articles: articles}
+	cache.Content["https://example.com/expired"] = Entry{Expire: past, Articles: articles}
+	cache.Content["https://example.com/orphaned"] = Entry{Expire: future, Articles: articles}
+	cache.Health["https://example.com/orphaned"] = Health{ConsecutiveFailures: 1}
+	cache.Extracted["https://example.com/article"] = "extracted"
+	cache.Extracted["https://example.com/saved"] = "extracted"
+	cache.AddToDownloaded(gofeed.Item{
+		Title:  "Saved",
+		Link:   "https://example.com/saved",
+		Custom: map[string]string{customAssets: "used"},
+	})
+
+	for _, name := range []string{"used", "orphaned"} {
+		if err = os.MkdirAll(filepath.Join(dir, "assets", name), 0755); err != nil {
+			t.Fatalf("couldn't create the assets: %v", err)
+		}
+	}
+
+	if stats := cache.Stats(); len(stats) != 3 || stats[0].URL != "https://example.com/expired" || stats[0].Size == 0 {
+		t.Errorf("expected the stats of every feed sorted by url, got %v", stats)
+	}
+
+	if removed := cache.Prune([]string{"https://example.com/kept", "https://example.com/expired"}); removed != 1 {
+		t.Errorf("expected only the orphaned entry to be pruned, got %d", removed)
+	}
+
+	if _, ok := cache.Content["https://example.com/expired"]; !ok || len(cache.Content) != 2 || len(cache.Health) != 0 {
+		t.Errorf("expected the entries of the subscribed feeds to stay, got %v and %v", cache.Content, cache.Health)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "assets", "orphaned")); !os.IsNotExist(err) {
+		t.Errorf("expected the orphaned assets to be removed")
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "assets", "used")); err != nil {
+		t.Errorf("expected the assets of the saved article to be kept: %v", err)
+	}
+
+	cache.Clear()
+	if len(cache.Content) != 0 || len(cache.GetDownloaded()) != 1 || len(cache.Extracted) != 1 {
+		t.Errorf("expected only the saved article and its extracted content to be kept")
+	}
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	if err = cache.Verify(); err != nil {
+		t.Errorf("expected the cache to pass verification: %v", err)
+	}
+
+	if err = os.RemoveAll(filepath.Join(dir, "assets", "used")); err != nil {
+		t.Fatalf("couldn't remove the assets: %v", err)
+	}
+
+	if err = cache.Verify(); err == nil {
+		t.Errorf("expected the missing images to be reported")
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "cache.json"), []byte("{\"content\": "), 0600); err != nil {
+		t.Fatalf("couldn't corrupt the cache: %v", err)
+	}
+
+	if err = cache.Verify(); err == nil {
+		t.Errorf("expected the corrupted cache to be reported")
+	}
+
+	readStatus, err := NewReadStatus(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the read status %v", err)
+	}
+
+	if err = readStatus.Verify(); err != nil {
+		t.Errorf("expected a missing read status to pass verification: %v", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "read_status"), []byte{1, 2, 3}, 0600); err != nil {
+		t.Fatalf("couldn't corrupt the read status: %v", err)
+	}
+
+	if err = readStatus.Verify(); err == nil {
+		t.Errorf("expected the corrupted read status to be reported")
+	}
+}
+
+// TestCacheMigrate if we get an error then the old cache files are not upgraded or the newer ones are loaded
+func TestCacheMigrate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.json")
+	original := `{"content": {"https://example.com/feed.xml": {"articles": [{"title": "Old", "link": "https://example.com/old"}]}}}`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("couldn't write the cache: %v", err)
+	}
+
+	cache, err := New(dir)
+	if err != nil {
+		t.Fatalf("couldn't create the cache %v", err)
+	}
+
+	if err = cache.Load(); err != nil {
+		t.Fatalf("couldn't load the cache: %v", err)
+	}
+
+	article := cache.Content["https://example.com/feed.xml"].Articles[0]
+	if feed := articleFeed(&article); feed != "https://example.com/feed.xml" {
+		t.Errorf("expected the article to know its feed, got %q", feed)
+	}
+
+	if backup, err := os.ReadFile(path + ".v0.bak"); err != nil || string(backup) != original {
+		t.Errorf("expected the original cache to be backed up, got %q (%v)", backup, err)
+	}
+
+	if err = cache.Save(); err != nil {
+		t.Fatalf("couldn't save the cache: %v", err)
+	}
+
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), `"version":1`) {
+		t.Errorf("expected the saved cache to be version 1, got %v", err)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"version": 99, "content": {}}`), 0600); err != nil {
+		t.Fatalf("couldn't write the cache: %v", err)
+	}
+
+	if err = cache.Load(); !errors.Is(err, migrate.ErrTooNew) {
+		t.Errorf("expected the newer cache to be refused, got %v", err)
+	}
+
+	if err = cache.Verify(); !errors.Is(err, migrate.ErrTooNew) {
+		t.Errorf("expected the newer cache to fail verification, got %v", err)
+	}
+}
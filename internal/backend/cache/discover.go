@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/TypicalAM/goread/internal/backend/rss"
+	"github.com/mmcdole/gofeed"
+)
+
+// ErrNoFeedsFound is returned when a website doesn't have any discoverable feeds
+var ErrNoFeedsFound = errors.New("no feeds found")
+
+// maxDiscoverySize is the maximum amount of bytes read from a page during discovery
+const maxDiscoverySize = 5 << 20
+
+// feedLinkTypes are the link types which websites use to advertise their feeds
+var feedLinkTypes = []string{"application/rss+xml", "application/atom+xml", "application/feed+json"}
+
+// commonFeedPaths are the paths which are probed if a website doesn't advertise its feeds
+var commonFeedPaths = []string{"feed", "rss", "index.xml", "feed.xml", "atom.xml", "rss.xml"}
+
+// Candidate is a feed found during autodiscovery
+type Candidate struct {
+	Title string
+	URL   string
+}
+
+// Name returns the name of the discovered feed, the name chosen by the user takes precedence over the feed title
+func (c Candidate) Name(chosen string) string {
+	if strings.TrimSpace(chosen) != "" {
+		return chosen
+	}
+
+	if c.Title != "" {
+		return c.Title
+	}
+
+	if parsed, err := url.Parse(c.URL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+
+	return c.URL
+}
+
+// Discover finds the feeds available at a url. If the url points to a feed, it is the only candidate. If it
+// points to a website, the feeds advertised by its <link rel="alternate"> tags are returned, and if there
+// are none some commonly used feed paths are probed. Feeds which aren't downloaded over http are returned as is.
+func (c *Cache) Discover(rawURL string) ([]Candidate, error) {
+	if !isHTTP(rawURL) {
+		return []Candidate{{URL: rawURL}}, nil
+	}
+
+	log.Println("Discovering feeds at", rawURL)
+	body, base, err := c.download(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("cache.Discover: %w", err)
+	}
+
+	if feed, err := gofeed.NewParser().Parse(bytes.NewReader(body)); err == nil {
+		return []Candidate{{Title: strings.TrimSpace(feed.Title), URL: rawURL}}, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cache.Discover: %w", err)
+	}
+
+	var links []string
+	doc.Find(`link[rel~="alternate"][href]`).Each(func(_ int, sel *goquery.Selection) {
+		linkType := strings.ToLower(strings.TrimSpace(sel.AttrOr("type", "")))
+		for _, feedType := range feedLinkTypes {
+			if linkType == feedType {
+				links = append(links, sel.AttrOr("href", ""))
+				return
+			}
+		}
+	})
+
+	candidates := c.probeLinks(base, links, false)
+	if len(candidates) == 0 {
+		probed := make([]string, 0, len(commonFeedPaths)*2)
+		for _, path := range commonFeedPaths {
+			probed = append(probed, path, "/"+path)
+		}
+
+		// Sites often serve the same feed under several of these paths, so the first hit is enough
+		candidates = c.probeLinks(base, probed, true)
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("cache.Discover: %w", ErrNoFeedsFound)
+	}
+
+	return candidates, nil
+}
+
+// probeLinks resolves the links against the base url and returns the ones which point to a valid feed
+func (c *Cache) probeLinks(base *url.URL, links []string, firstOnly bool) []Candidate {
+	seen := make(map[string]struct{}, len(links))
+	candidates := make([]Candidate, 0)
+
+	for _, link := range links {
+		ref, err := url.Parse(strings.TrimSpace(link))
+		if err != nil {
+			continue
+		}
+
+		resolved := base.ResolveReference(ref).String()
+		if _, ok := seen[resolved]; ok {
+			continue
+		}
+
+		seen[resolved] = struct{}{}
+		body, _, err := c.download(resolved)
+		if err != nil {
+			log.Println("Discovery probe failed for", resolved, err)
+			continue
+		}
+
+		feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+
+		candidates = append(candidates, Candidate{Title: strings.TrimSpace(feed.Title), URL: resolved})
+		if firstOnly {
+			break
+		}
+	}
+
+	return candidates
+}
+
+// download gets the contents of a url, the final url after following redirects is also returned
+func (c *Cache) download(rawURL string) ([]byte, *url.URL, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultFetchTimeout)
+	defer cancel()
+
+	req, err := newRequest(ctx, rawURL, rss.FetchOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.fetcher().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, newStatusError(resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoverySize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, responseURL(resp, req), nil
+}
+
+// responseURL returns the final url of a response after following redirects. A Fetcher doesn't have to
+// set the request of the response, the url of the request it was given is used then.
+func responseURL(resp *http.Response, req *http.Request) *url.URL {
+	if resp.Request != nil && resp.Request.URL != nil {
+		return resp.Request.URL
+	}
+
+	return req.URL
+}
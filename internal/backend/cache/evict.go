@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// touch marks the feed as used, which keeps it from being evicted. Reading a feed doesn't make the cache
+// dirty, the time it was used is written along with the next change.
+func (c *Cache) touch(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.Content[url]; ok {
+		entry.LastUsed = time.Now()
+		c.Content[url] = entry
+	}
+}
+
+// cacheSize returns the amount of articles in the cache, the downloaded articles don't count
+func (c *Cache) cacheSize() int {
+	size := 0
+	for _, entry := range c.Content {
+		size += len(entry.Articles)
+	}
+
+	return size
+}
+
+// evict removes the articles of the least recently used feeds until the cache fits in DefaultCacheSize.
+// Whole feeds are evicted, only the last one is trimmed to its newest articles if that is enough. The
+// downloaded articles live outside of the feed entries and are never evicted, neither are the entries without
+// articles, since they take up no space and keep the validators of the feeds. The caller must hold the lock.
+func (c *Cache) evict() {
+	size := c.cacheSize()
+	log.Println("Cache size:", size, "articles in", len(c.Content), "feeds, the limit is", DefaultCacheSize)
+	if DefaultCacheSize <= 0 || size <= DefaultCacheSize {
+		return
+	}
+
+	urls := make([]string, 0, len(c.Content))
+	for url := range c.Content {
+		urls = append(urls, url)
+	}
+
+	sort.Slice(urls, func(a, b int) bool {
+		first, second := c.Content[urls[a]].LastUsed, c.Content[urls[b]].LastUsed
+		if first.Equal(second) {
+			return urls[a] < urls[b]
+		}
+
+		return first.Before(second)
+	})
+
+	for _, url := range urls {
+		overflow := size - DefaultCacheSize
+		if overflow <= 0 {
+			break
+		}
+
+		entry := c.Content[url]
+		if len(entry.Articles) == 0 {
+			continue
+		}
+
+		if overflow >= len(entry.Articles) {
+			log.Println("Evicting", url, "with", len(entry.Articles), "articles, last used", entry.LastUsed)
+			delete(c.Content, url)
+			size -= len(entry.Articles)
+			continue
+		}
+
+		log.Println("Evicting the", overflow, "oldest articles of", url, "last used", entry.LastUsed)
+		sort.Sort(entry.Articles)
+		entry.Articles = append(SortableArticles(nil), entry.Articles[:len(entry.Articles)-overflow]...)
+		keys := articleKeys(entry.Articles)
+		for key := range entry.Seen {
+			if _, ok := keys[key]; !ok {
+				delete(entry.Seen, key)
+			}
+		}
+
+		c.Content[url] = entry
+		size -= overflow
+	}
+
+	log.Println("Cache size after eviction:", size, "articles in", len(c.Content), "feeds")
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TypicalAM/goread/internal/backend/rss"
+	"github.com/mmcdole/gofeed"
+	gofeedrss "github.com/mmcdole/gofeed/rss"
+)
+
+// DefaultMinCacheDuration is the shortest time a feed is cached for, no matter what the feed asks for
+var DefaultMinCacheDuration = 5 * time.Minute
+
+// DefaultMaxCacheDuration is the longest time a feed is cached for, no matter what the feed asks for
+var DefaultMaxCacheDuration = 7 * 24 * time.Hour
+
+// The keys under which the rss refresh hints are kept in gofeed's custom elements, since it doesn't translate them
+const (
+	customTTL       = "goread:ttl"
+	customSkipHours = "goread:skip_hours"
+	customSkipDays  = "goread:skip_days"
+)
+
+// syndicationPeriods are the periods allowed in the sy:updatePeriod element
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// RefreshHints are the hints a feed gives about how often it should be refreshed
+type RefreshHints struct {
+	Interval  time.Duration  `json:"interval,omitempty"`
+	SkipHours []int          `json:"skip_hours,omitempty"`
+	SkipDays  []time.Weekday `json:"skip_days,omitempty"`
+}
+
+// hintTranslator translates rss feeds just like gofeed does, but keeps the refresh hints which gofeed drops
+type hintTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+// Translate fulfills the gofeed.Translator interface
+func (t *hintTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	rssFeed, ok := feed.(*gofeedrss.Feed)
+	if !ok {
+		return result, nil
+	}
+
+	if result.Custom == nil {
+		result.Custom = make(map[string]string)
+	}
+
+	if ttl := strings.TrimSpace(rssFeed.TTL); ttl != "" {
+		result.Custom[customTTL] = ttl
+	}
+
+	if len(rssFeed.SkipHours) != 0 {
+		result.Custom[customSkipHours] = strings.Join(rssFeed.SkipHours, ",")
+	}
+
+	if len(rssFeed.SkipDays) != 0 {
+		result.Custom[customSkipDays] = strings.Join(rssFeed.SkipDays, ",")
+	}
+
+	return result, nil
+}
+
+// newParser creates a feed parser which keeps the refresh hints of rss feeds
+func newParser() *gofeed.Parser {
+	parser := gofeed.NewParser()
+	parser.RSSTranslator = &hintTranslator{}
+	return parser
+}
+
+// feedHints collects the refresh hints from a parsed feed: the rss ttl, the syndication
+// module's update period and the hours and days during which the feed shouldn't be fetched
+func feedHints(feed *gofeed.Feed) RefreshHints {
+	var hints RefreshHints
+	if minutes, err := strconv.Atoi(feed.Custom[customTTL]); err == nil && minutes > 0 {
+		hints.Interval = time.Duration(minutes) * time.Minute
+	}
+
+	if period := syndicationInterval(feed); period > hints.Interval {
+		hints.Interval = period
+	}
+
+	for _, value := range strings.Split(feed.Custom[customSkipHours], ",") {
+		if hour, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && hour >= 0 && hour < 24 {
+			hints.SkipHours = append(hints.SkipHours, hour)
+		}
+	}
+
+	for _, value := range strings.Split(feed.Custom[customSkipDays], ",") {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(strings.TrimSpace(value), day.String()) {
+				hints.SkipDays = append(hints.SkipDays, day)
+			}
+		}
+	}
+
+	return hints
+}
+
+// syndicationInterval returns the update interval described by the sy:updatePeriod and sy:updateFrequency elements
+func syndicationInterval(feed *gofeed.Feed) time.Duration {
+	sy, ok := feed.Extensions["sy"]
+	if !ok || len(sy["updatePeriod"]) == 0 {
+		return 0
+	}
+
+	period, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(sy["updatePeriod"][0].Value))]
+	if !ok {
+		return 0
+	}
+
+	frequency := 1
+	if len(sy["updateFrequency"]) != 0 {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(sy["updateFrequency"][0].Value)); err == nil && parsed > 0 {
+			frequency = parsed
+		}
+	}
+
+	return period / time.Duration(frequency)
+}
+
+// headerInterval returns how long the response may be cached according to the Cache-Control and Expires headers
+func headerInterval(header http.Header, now time.Time) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+
+		if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+
+		return 0
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil && expires.After(now) {
+		return expires.Sub(now)
+	}
+
+	return 0
+}
+
+// entryExpiry decides when a feed should be refreshed. The refresh interval set by the user wins,
+// otherwise the longest of the feed and http hints is used, clamped to a sane range. If the feed
+// asks us to skip some hours or days, the expiry is moved past them.
+func entryExpiry(opts rss.FetchOptions, hints RefreshHints, header http.Header, now time.Time) time.Time {
+	if opts.RefreshInterval > 0 {
+		return now.Add(opts.RefreshInterval)
+	}
+
+	interval := hints.Interval
+	if fromHeader := headerInterval(header, now); fromHeader > interval {
+		interval = fromHeader
+	}
+
+	switch {
+	case interval == 0:
+		interval = DefaultCacheDuration
+
+	case interval < DefaultMinCacheDuration:
+		interval = DefaultMinCacheDuration
+
+	case interval > DefaultMaxCacheDuration:
+		interval = DefaultMaxCacheDuration
+	}
+
+	return skipUntil(hints, now.Add(interval))
+}
+
+// skipUntil moves the expiry to the first hour which isn't skipped, the skipped hours are in GMT
+func skipUntil(hints RefreshHints, expire time.Time) time.Time {
+	if len(hints.SkipHours) == 0 && len(hints.SkipDays) == 0 {
+		return expire
+	}
+
+	for i := 0; i < 7*24 && isSkipped(hints, expire.UTC()); i++ {
+		expire = expire.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return expire
+}
+
+// isSkipped checks if the feed asked not to be fetched at the given time
+func isSkipped(hints RefreshHints, t time.Time) bool {
+	for _, hour := range hints.SkipHours {
+		if t.Hour() == hour {
+			return true
+		}
+	}
+
+	for _, day := range hints.SkipDays {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/TypicalAM/goread/internal/backend/rss"
+)
+
+// maxArticleSize is the maximum amount of bytes read from an article page
+const maxArticleSize = 10 << 20
+
+// ErrNoContent is returned when the main content of an article page cannot be found
+var ErrNoContent = errors.New("no article content found")
+
+// clutterSelector matches the elements which are never part of the article content
+const clutterSelector = "script, style, noscript, template, iframe, svg, canvas, form, button, input, select, " +
+	"nav, header, footer, aside, [role=navigation], [role=banner], [role=contentinfo], [role=complementary], " +
+	"[aria-hidden=true], [hidden]"
+
+// clutterPattern matches the class names and ids of navigation, ads and other page furniture
+var clutterPattern = regexp.MustCompile(`(?i)(^|[\s_-])(ads?|advert\w*|banner|breadcrumbs?|comments?|cookie\w*|` +
+	`footer|header|menu|nav\w*|newsletter|popup|promo\w*|related|share|sharing|sidebar|social|sponsor\w*|` +
+	`subscribe|widget)($|[\s_-])`)
+
+// contentPattern matches the class names and ids which usually hold the article content
+var contentPattern = regexp.MustCompile(`(?i)(article|body|content|entry|main|post|story|text)`)
+
+// Extract downloads an article page and converts its main content to markdown. Navigation, ads and
+// other clutter are stripped, and the element with the most paragraph text is taken as the content.
+func (c *Cache) Extract(rawURL string, opts rss.FetchOptions) (string, error) {
+	log.Println("Extracting the article at", rawURL)
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(opts))
+	defer cancel()
+
+	req, err := newRequest(ctx, rawURL, opts)
+	if err != nil {
+		return "", fmt.Errorf("cache.Extract: %w", err)
+	}
+
+	resp, err := c.fetcher().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cache.Extract: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("cache.Extract: %w", newStatusError(resp))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArticleSize))
+	if err != nil {
+		return "", fmt.Errorf("cache.Extract: %w", err)
+	}
+
+	content, err := extractContent(body, responseURL(resp, req))
+	if err != nil {
+		return "", fmt.Errorf("cache.Extract: %w", err)
+	}
+
+	return content, nil
+}
+
+// extractContent finds the main content of a page and converts it to markdown
+func extractContent(page []byte, base *url.URL) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+
+	doc.Find(clutterSelector).Remove()
+	doc.Find("body [class], body [id]").FilterFunction(func(_ int, sel *goquery.Selection) bool {
+		attrs := sel.AttrOr("class", "") + " " + sel.AttrOr("id", "")
+		return clutterPattern.MatchString(attrs) && !contentPattern.MatchString(attrs)
+	}).Remove()
+
+	article := findMainContent(doc)
+	if article == nil {
+		return "", ErrNoContent
+	}
+
+	resolveLinks(article, base)
+	html, err := goquery.OuterHtml(article)
+	if err != nil {
+		return "", err
+	}
+
+	markdown, err := rss.HTMLToMarkdown(html)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(markdown) == "" {
+		return "", ErrNoContent
+	}
+
+	return markdown, nil
+}
+
+// findMainContent returns the element which most likely holds the article, preferring the semantic
+// elements and falling back to the container with the most paragraph text
+func findMainContent(doc *goquery.Document) *goquery.Selection {
+	for _, selector := range []string{"article", "main", "[role=main]", "[itemprop=articleBody]"} {
+		if best := longestText(doc.Find(selector)); best != nil {
+			return best
+		}
+	}
+
+	var best *goquery.Selection
+	bestScore := 0
+	doc.Find("div, section, td").Each(func(_ int, sel *goquery.Selection) {
+		score := 0
+		sel.ChildrenFiltered("p, pre, blockquote").Each(func(_ int, p *goquery.Selection) {
+			score += len(strings.TrimSpace(p.Text()))
+		})
+
+		attrs := sel.AttrOr("class", "") + " " + sel.AttrOr("id", "")
+		if contentPattern.MatchString(attrs) {
+			score += score / 4
+		}
+
+		if score > bestScore {
+			best, bestScore = sel, score
+		}
+	})
+
+	if best != nil {
+		return best
+	}
+
+	if body := doc.Find("body"); len(strings.TrimSpace(body.Text())) != 0 {
+		return body
+	}
+
+	return nil
+}
+
+// longestText returns the element of the selection with the most text, nil if none of them has any
+func longestText(sel *goquery.Selection) *goquery.Selection {
+	var best *goquery.Selection
+	bestLength := 0
+	sel.Each(func(_ int, candidate *goquery.Selection) {
+		if length := len(strings.TrimSpace(candidate.Text())); length > bestLength {
+			best, bestLength = candidate, length
+		}
+	})
+
+	return best
+}
+
+// resolveLinks makes the links and images in the content absolute, so that they work outside the page
+func resolveLinks(sel *goquery.Selection, base *url.URL) {
+	for attr, selector := range map[string]string{"href": "a[href]", "src": "img[src]"} {
+		sel.Find(selector).Each(func(_ int, elem *goquery.Selection) {
+			ref, err := url.Parse(strings.TrimSpace(elem.AttrOr(attr, "")))
+			if err != nil {
+				return
+			}
+
+			elem.SetAttr(attr, base.ResolveReference(ref).String())
+		})
+	}
+}
+
+// GetExtracted returns the extracted content of an article, if it was extracted before
+func (c *Cache) GetExtracted(link string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	content, ok := c.Extracted[link]
+	return content, ok
+}
+
+// GetFullContent returns the full content of an article, extracting it from the article page if
+// it isn't cached yet. In offline mode only the cached content is available. A failed extraction
+// is recorded, so that ExtractNew leaves the article alone for a while.
+func (c *Cache) GetFullContent(link string, opts rss.FetchOptions) (string, error) {
+	if content, ok := c.GetExtracted(link); ok {
+		return content, nil
+	}
+
+	if c.OfflineMode {
+		return "", errors.New("offline mode")
+	}
+
+	content, err := c.Extract(link, opts)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dirty = true
+	if err != nil {
+		failed := c.ExtractFailures[link]
+		failed.ConsecutiveFailures++
+		failed.LastError = err.Error()
+		failed.LastFailure = time.Now()
+		c.ExtractFailures[link] = failed
+		return "", fmt.Errorf("cache.GetFullContent: %w", err)
+	}
+
+	c.Extracted[link] = content
+	delete(c.ExtractFailures, link)
+	return content, nil
+}
+
+// ExtractNew extracts the full content of the articles from the last fetch of the feed, the older history
+// is left alone. Articles which failed to extract are retried once their backoff expires, the pages are
+// downloaded concurrently using the same limits as GetArticlesBulk. It returns the amount of extracted articles.
+func (c *Cache) ExtractNew(feed *rss.Feed) int {
+	if c.OfflineMode {
+		return 0
+	}
+
+	links := c.pendingExtractions(feed, time.Now())
+	if len(links) == 0 {
+		return 0
+	}
+
+	var extracted int32
+	fetchAll(links, func(i int) {
+		if _, err := c.GetFullContent(links[i], feed.FetchOptions); err != nil {
+			log.Println("Cannot extract the article", links[i], err)
+		} else {
+			atomic.AddInt32(&extracted, 1)
+		}
+	})
+
+	return int(extracted)
+}
+
+// pendingExtractions returns the links of the articles from the last fetch of the feed which weren't
+// extracted yet, skipping the ones whose last extraction failed too recently
+func (c *Cache) pendingExtractions(feed *rss.Feed, now time.Time) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry := c.Content[feed.URL]
+	var latest time.Time
+	for _, seen := range entry.Seen {
+		if seen.After(latest) {
+			latest = seen
+		}
+	}
+
+	var links []string
+	for _, article := range filterArticles(feed, entry.Articles) {
+		if article.Link == "" || !entry.Seen[articleKey(&article)].Equal(latest) {
+			continue
+		}
+
+		if _, ok := c.Extracted[article.Link]; ok {
+			continue
+		}
+
+		if failed, ok := c.ExtractFailures[article.Link]; ok && now.Before(failed.LastFailure.Add(failureBackoff(failed.ConsecutiveFailures, nil))) {
+			continue
+		}
+
+		links = append(links, article.Link)
+	}
+
+	return links
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFetchRetries is the default amount of times a failed download is retried
+var DefaultFetchRetries = 2
+
+// DefaultRetryBackoff is the delay before the first retry, every next retry waits twice as long
+var DefaultRetryBackoff = time.Second
+
+// DefaultMaxRetryWait is the longest Retry-After we are willing to wait for before giving up on a download
+var DefaultMaxRetryWait = 30 * time.Second
+
+// DefaultFailureBackoff is how long a failed feed is left alone after its first failure, it doubles
+// with every consecutive failure up to DefaultCacheDuration
+var DefaultFailureBackoff = 5 * time.Minute
+
+// Health keeps track of how reliably a feed can be downloaded
+type Health struct {
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailure         time.Time `json:"last_failure"`
+	LastSuccess         time.Time `json:"last_success"`
+}
+
+// Failing returns true if the last download of the feed failed
+func (h Health) Failing() bool {
+	return h.ConsecutiveFailures > 0
+}
+
+// statusError is returned when the server responds with an unexpected status code
+type statusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+}
+
+// Error fulfills the error interface
+func (e statusError) Error() string {
+	return fmt.Sprintf("http error: %s", e.Status)
+}
+
+// newStatusError creates a statusError from a response, reading its Retry-After header
+func newStatusError(resp *http.Response) statusError {
+	return statusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a delay in seconds or a date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+// retryAfter returns the delay requested by the server, if any
+func retryAfter(err error) time.Duration {
+	var statusErr statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+
+	return 0
+}
+
+// isRetryable checks if a download error is likely to go away on its own
+func isRetryable(err error) bool {
+	var statusErr statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryDelay returns how long to wait before retrying a failed download, false is
+// returned if the download shouldn't be retried at all
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	if attempt >= DefaultFetchRetries || !isRetryable(err) {
+		return 0, false
+	}
+
+	if wait := retryAfter(err); wait > 0 {
+		return wait, wait <= DefaultMaxRetryWait
+	}
+
+	return DefaultRetryBackoff << attempt, true
+}
+
+// failureBackoff returns how long a feed with the given amount of consecutive failures should be left alone
+func failureBackoff(failures int, err error) time.Duration {
+	backoff := DefaultFailureBackoff
+	for i := 1; i < failures && backoff < DefaultCacheDuration; i++ {
+		backoff *= 2
+	}
+
+	if backoff > DefaultCacheDuration {
+		backoff = DefaultCacheDuration
+	}
+
+	if wait := retryAfter(err); wait > backoff {
+		backoff = wait
+	}
+
+	return backoff
+}
+
+// GetHealth returns the health record of a feed
+func (c *Cache) GetHealth(url string) (Health, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	health, ok := c.Health[url]
+	return health, ok
+}
+
+// recordSuccess notes that the feed was downloaded successfully
+func (c *Cache) recordSuccess(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := c.Health[url]
+	health.ConsecutiveFailures = 0
+	health.LastError = ""
+	health.LastSuccess = time.Now()
+	c.Health[url] = health
+	c.dirty = true
+}
+
+// recordFailure notes that downloading the feed failed and keeps the stale entry around
+// until the backoff expires, so that the feed isn't hammered with requests
+func (c *Cache) recordFailure(url string, cached Entry, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := c.Health[url]
+	health.ConsecutiveFailures++
+	health.LastError = err.Error()
+	health.LastFailure = time.Now()
+	c.Health[url] = health
+
+	if cached.Articles == nil {
+		cached.Articles = SortableArticles{}
+	}
+
+	cached.Expire = time.Now().Add(failureBackoff(health.ConsecutiveFailures, err))
+	c.Content[url] = cached
+	c.dirty = true
+}
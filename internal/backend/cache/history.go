@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultHistoryAge is how long an article is kept after it dropped off its feed, zero keeps them forever
+var DefaultHistoryAge = 30 * 24 * time.Hour
+
+// DefaultHistorySize is the maximum amount of articles kept per feed, zero means no limit
+var DefaultHistorySize = 500
+
+// mergeArticles merges freshly fetched articles into the history of a feed. The articles are matched
+// by their key, a fetched article replaces its older version and new articles are added. Every fetched
+// article is marked as seen now, the rest keep the time they were last seen in the feed.
+func (e *Entry) mergeArticles(fetched SortableArticles, now time.Time) {
+	if e.Seen == nil {
+		e.Seen = make(map[string]time.Time)
+	}
+
+	merged := make(SortableArticles, 0, len(e.Articles)+len(fetched))
+	positions := make(map[string]int, len(e.Articles)+len(fetched))
+	for _, articles := range []SortableArticles{e.Articles, fetched} {
+		for i := range articles {
+			key := articleKey(&articles[i])
+			if pos, ok := positions[key]; ok {
+				merged[pos] = articles[i]
+				continue
+			}
+
+			positions[key] = len(merged)
+			merged = append(merged, articles[i])
+		}
+	}
+
+	for i := range fetched {
+		e.Seen[articleKey(&fetched[i])] = now
+	}
+
+	sort.Sort(merged)
+	e.Articles = merged
+}
+
+// markSeen marks the articles from the last fetch as seen now, it is used when the server
+// says that the feed didn't change and so the same articles are still in it
+func (e *Entry) markSeen(now time.Time) {
+	var latest time.Time
+	for _, seen := range e.Seen {
+		if seen.After(latest) {
+			latest = seen
+		}
+	}
+
+	for key, seen := range e.Seen {
+		if seen.Equal(latest) {
+			e.Seen[key] = now
+		}
+	}
+}
+
+// pruneHistory drops the articles which dropped off the feed more than DefaultHistoryAge ago and
+// then keeps at most DefaultHistorySize articles, preferring the ones which were seen last
+func (e *Entry) pruneHistory(now time.Time) {
+	if e.Seen == nil {
+		e.Seen = make(map[string]time.Time)
+	}
+
+	kept := make(SortableArticles, 0, len(e.Articles))
+	for i := range e.Articles {
+		key := articleKey(&e.Articles[i])
+		seen, ok := e.Seen[key]
+		if !ok {
+			// Articles cached before the history was introduced start their retention now
+			seen = now
+			e.Seen[key] = now
+		}
+
+		if DefaultHistoryAge <= 0 || now.Sub(seen) <= DefaultHistoryAge {
+			kept = append(kept, e.Articles[i])
+		}
+	}
+
+	if DefaultHistorySize > 0 && len(kept) > DefaultHistorySize {
+		sort.SliceStable(kept, func(a, b int) bool {
+			return e.Seen[articleKey(&kept[a])].After(e.Seen[articleKey(&kept[b])])
+		})
+
+		kept = kept[:DefaultHistorySize]
+		sort.Sort(kept)
+	}
+
+	keys := articleKeys(kept)
+	for key := range e.Seen {
+		if _, ok := keys[key]; !ok {
+			delete(e.Seen, key)
+		}
+	}
+
+	e.Articles = kept
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/TypicalAM/goread/internal/migrate"
+)
+
+// EntryStats describes a single feed in the cache
+type EntryStats struct {
+	URL      string
+	Articles int
+	Size     int
+	Expire   time.Time
+	LastUsed time.Time
+	Health   Health
+}
+
+// Stats returns the statistics of every cached feed, sorted by the feed url. The size is the
+// amount of bytes the entry takes up in the cache file.
+func (c *Cache) Stats() []EntryStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := make([]EntryStats, 0, len(c.Content))
+	for url, entry := range c.Content {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			log.Println("Cannot measure the entry of", url, err)
+		}
+
+		stats = append(stats, EntryStats{
+			URL:      url,
+			Articles: len(entry.Articles),
+			Size:     len(data),
+			Expire:   entry.Expire,
+			LastUsed: entry.LastUsed,
+			Health:   c.Health[url],
+		})
+	}
+
+	sort.Slice(stats, func(a, b int) bool { return stats[a].URL < stats[b].URL })
+	return stats
+}
+
+// Prune removes the entries of the feeds which aren't in the given list of urls anymore, along with their
+// health records. The expired entries of the subscribed feeds are kept, since they hold the article history
+// which is trimmed by the history retention setting instead. The saved images no saved article refers to are
+// removed too. It returns the amount of removed entries.
+func (c *Cache) Prune(urls []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	known := make(map[string]struct{}, len(urls))
+	for _, url := range urls {
+		known[url] = struct{}{}
+	}
+
+	removed := 0
+	for url := range c.Content {
+		if _, ok := known[url]; ok {
+			continue
+		}
+
+		delete(c.Content, url)
+		removed++
+	}
+
+	for url := range c.Health {
+		if _, ok := known[url]; !ok {
+			delete(c.Health, url)
+		}
+	}
+
+	c.pruneAssets()
+	c.dirty = true
+	return removed
+}
+
+// pruneAssets removes the asset directories which don't belong to any saved article. The caller must hold the lock.
+func (c *Cache) pruneAssets() {
+	entries, err := os.ReadDir(filepath.Join(c.Dir(), "assets"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("Cannot list the saved assets", err)
+		}
+
+		return
+	}
+
+	used := make(map[string]struct{}, len(c.Downloaded))
+	for i := range c.Downloaded {
+		used[c.Downloaded[i].Custom[customAssets]] = struct{}{}
+	}
+
+	for _, entry := range entries {
+		if _, ok := used[entry.Name()]; ok || !entry.IsDir() {
+			continue
+		}
+
+		log.Println("Removing the orphaned assets", entry.Name())
+		if err = os.RemoveAll(filepath.Join(c.Dir(), "assets", entry.Name())); err != nil {
+			log.Println("Cannot remove the orphaned assets", entry.Name(), err)
+		}
+	}
+}
+
+// Clear removes every cached feed along with their health records. The downloaded articles and the
+// content extracted for them are kept.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	saved := make(map[string]struct{}, len(c.Downloaded))
+	for i := range c.Downloaded {
+		saved[c.Downloaded[i].Link] = struct{}{}
+	}
+
+	for link := range c.Extracted {
+		if _, ok := saved[link]; !ok {
+			delete(c.Extracted, link)
+		}
+	}
+
+	c.Content = make(map[string]Entry)
+	c.Health = make(map[string]Health)
+	c.ExtractFailures = make(map[string]Health)
+	c.dirty = true
+}
+
+// Verify checks that the cache file on disk can be read and that the saved articles still have their
+// images, the cache itself is left untouched. A missing cache file is not an error.
+func (c *Cache) Verify() error {
+	data, err := os.ReadFile(c.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("cache.Verify: %w", err)
+	}
+
+	var file Cache
+	if err = json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cache.Verify: %s is corrupted: %w", c.filePath, err)
+	}
+
+	if file.Version > cacheVersion {
+		return fmt.Errorf("cache.Verify: %w: %s is version %d", migrate.ErrTooNew, c.filePath, file.Version)
+	}
+
+	missing := 0
+	for i := range file.Downloaded {
+		dirName := file.Downloaded[i].Custom[customAssets]
+		if dirName == "" {
+			continue
+		}
+
+		if _, err = os.Stat(filepath.Join(c.Dir(), "assets", dirName)); err != nil {
+			missing++
+		}
+	}
+
+	if missing != 0 {
+		return fmt.Errorf("cache.Verify: %d saved articles are missing their images", missing)
+	}
+
+	return nil
+}
+
+// Verify checks that the read status file on disk can be read, the old binary file is checked if it
+// wasn't migrated yet. The read status itself is left untouched. A missing file is not an error.
+func (rs *ReadStatus) Verify() error {
+	data, err := os.ReadFile(rs.filePath)
+	if os.IsNotExist(err) {
+		data, err = os.ReadFile(rs.legacyPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("cache.Verify: %w", err)
+		}
+
+		if _, err = unmarshal(data); err != nil {
+			return fmt.Errorf("cache.Verify: %s is corrupted: %w", rs.legacyPath, err)
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("cache.Verify: %w", err)
+	}
+
+	var file readStatusFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cache.Verify: %s is corrupted: %w", rs.filePath, err)
+	}
+
+	if file.Version > readStatusVersion {
+		return fmt.Errorf("cache.Verify: %w: %s is version %d", migrate.ErrTooNew, rs.filePath, file.Version)
+	}
+
+	return nil
+}
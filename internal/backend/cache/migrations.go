@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/TypicalAM/goread/internal/migrate"
+)
+
+// cacheVersion is the version of the cache file format
+const cacheVersion = 1
+
+// cacheSchema describes the versions of the cache file
+var cacheSchema = migrate.Schema{
+	Name:    "cache",
+	Format:  migrate.JSON,
+	Version: cacheVersion,
+	Migrations: map[int]migrate.Migration{
+		0: stampArticleFeeds,
+	},
+}
+
+// readStatusSchema describes the versions of the read status file, the first version was the binary
+// file which is migrated separately
+var readStatusSchema = migrate.Schema{
+	Name:    "read status",
+	Format:  migrate.JSON,
+	Version: readStatusVersion,
+}
+
+// stampArticleFeeds upgrades the cache from version 0, the articles cached before it didn't know which
+// feed they came from
+func stampArticleFeeds(data []byte) ([]byte, error) {
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cache.stampArticleFeeds: %w", err)
+	}
+
+	raw, ok := file["content"]
+	if !ok {
+		return data, nil
+	}
+
+	var content map[string]Entry
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, fmt.Errorf("cache.stampArticleFeeds: %w", err)
+	}
+
+	for url, entry := range content {
+		for i := range entry.Articles {
+			if articleFeed(&entry.Articles[i]) == "" {
+				setArticleFeed(&entry.Articles[i], url)
+			}
+		}
+	}
+
+	var err error
+	if file["content"], err = json.Marshal(content); err != nil {
+		return nil, fmt.Errorf("cache.stampArticleFeeds: %w", err)
+	}
+
+	return json.Marshal(file)
+}
@@ -2,21 +2,57 @@ package cache
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/mmcdole/gofeed"
 	"github.com/spaolacci/murmur3"
+
+	"github.com/TypicalAM/goread/internal/atomicfile"
+	"github.com/TypicalAM/goread/internal/migrate"
 )
 
-// ReadStatus is a set containing the hashes of the already read articles. We use a struct{} here
-// because it takes up no space in memory. To hash the article, we use the URL of the feed and the title
-// of the article.
+// DefaultReadStatusAge is how long an article is remembered as read, zero remembers it forever
+var DefaultReadStatusAge = 365 * 24 * time.Hour
+
+// readStatusVersion is the version of the read status file format, the first version was a bare set of hashes
+const readStatusVersion = 2
+
+// ArticleID identifies an article in the read status. The feed is a part of it, so that the same
+// article in two feeds (or two articles without a link) don't share their read status.
+type ArticleID struct {
+	Feed string
+	Key  string
+}
+
+// NewArticleID returns the id of an article, the article is keyed by its guid, its link or its title
+func NewArticleID(item *gofeed.Item) ArticleID {
+	return ArticleID{Feed: articleFeed(item), Key: articleKey(item)}
+}
+
+// readStatusFile is the format of the read status file
+type readStatusFile struct {
+	Version int                             `json:"version"`
+	Read    map[string]map[string]time.Time `json:"read"`
+	Legacy  []uint32                        `json:"legacy,omitempty"`
+}
+
+// ReadStatus keeps the articles which were already read along with the time they were read at. It
+// is stored per feed, keyed by the article key. The hashes from the old format are only kept until
+// they are converted to the new keys, see ConvertLegacy.
 type ReadStatus struct {
-	set      map[uint32]struct{}
-	filePath string
+	read       map[string]map[string]time.Time
+	legacy     map[uint32]struct{}
+	filePath   string
+	legacyPath string
+	mu         sync.Mutex
+	dirty      bool
 }
 
 // New creates a new ReadStatus set.
@@ -32,77 +68,210 @@ func NewReadStatus(dir string) (*ReadStatus, error) {
 	}
 
 	return &ReadStatus{
-		filePath: filepath.Join(dir, "read_status"),
-		set:      make(map[uint32]struct{}),
+		filePath:   filepath.Join(dir, "read_status.json"),
+		legacyPath: filepath.Join(dir, "read_status"),
+		read:       make(map[string]map[string]time.Time),
+		legacy:     make(map[uint32]struct{}),
 	}, nil
 }
 
-// Load reads the cache from disk
+// Load reads the read status from disk, the old binary format is migrated if there is no new file yet
 func (rs *ReadStatus) Load() error {
 	log.Println("Loading read status from", rs.filePath)
 	data, err := os.ReadFile(rs.filePath)
+	if os.IsNotExist(err) {
+		return rs.migrate()
+	}
+
+	if err != nil {
+		return fmt.Errorf("cache.Load: %w", err)
+	}
+
+	if data, err = readStatusSchema.Upgrade(rs.filePath, data); err != nil {
+		return fmt.Errorf("cache.Load: %w", err)
+	}
+
+	var file readStatusFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cache.Load: %w", err)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.read = file.Read
+	if rs.read == nil {
+		rs.read = make(map[string]map[string]time.Time)
+	}
+
+	rs.legacy = make(map[uint32]struct{}, len(file.Legacy))
+	for _, hash := range file.Legacy {
+		rs.legacy[hash] = struct{}{}
+	}
+
+	return nil
+}
+
+// migrate loads the hashes from the old binary read status file, they have to be converted with ConvertLegacy
+func (rs *ReadStatus) migrate() error {
+	data, err := os.ReadFile(rs.legacyPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil
 		}
 
-		return fmt.Errorf("cache.Load: %w", err)
+		return fmt.Errorf("cache.migrate: %w", err)
 	}
 
-	rs.set, err = unmarshal(data)
+	legacy, err := unmarshal(data)
 	if err != nil {
-		return fmt.Errorf("cache.Load: %w", err)
+		return fmt.Errorf("cache.migrate: %w", err)
 	}
 
+	log.Println("Migrating", len(legacy), "entries from the old read status file")
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.legacy = legacy
+	rs.dirty = true
 	return nil
 }
 
-// Save writes the cache to disk
-func (rs ReadStatus) Save() error {
-	data := marshal(rs.set)
-	log.Println("Marshalling the data yielded a size of", len(data))
+// ConvertLegacy converts the hashes from the old format to the new keys by matching them against the links
+// of the articles in the cache. The hashes which don't match any of them are dropped, looking them up for
+// every article could mark the unrelated articles with a colliding hash as read. It returns the amount of
+// converted articles.
+func (rs *ReadStatus) ConvertLegacy(c *Cache) int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if len(rs.legacy) == 0 {
+		return 0
+	}
+
+	converted := 0
+	now := time.Now()
+	c.eachArticle(func(item *gofeed.Item) {
+		if item.Link == "" {
+			return
+		}
+
+		if _, ok := rs.legacy[hashArticle(item.Link)]; !ok {
+			return
+		}
 
-	// Try to write the data to the file
-	if err := os.WriteFile(rs.filePath, data, 0600); err != nil {
-		if err = os.MkdirAll(filepath.Dir(rs.filePath), 0755); err != nil {
-			return fmt.Errorf("cache.Save: %w", err)
+		id := NewArticleID(item)
+		if rs.read[id.Feed] == nil {
+			rs.read[id.Feed] = make(map[string]time.Time)
 		}
 
-		if err = os.WriteFile(rs.filePath, data, 0600); err != nil {
-			return fmt.Errorf("cache.Save: %w", err)
+		if _, ok := rs.read[id.Feed][id.Key]; !ok {
+			rs.read[id.Feed][id.Key] = now
+			converted++
 		}
+	})
+
+	log.Println("Converted", converted, "articles from the old read status, forgetting", len(rs.legacy), "hashes")
+	rs.legacy = make(map[uint32]struct{})
+	rs.dirty = true
+	return converted
+}
+
+// Dirty reports if the read status changed since it was last saved
+func (rs *ReadStatus) Dirty() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.dirty
+}
+
+// Save prunes the old entries and writes the read status to disk, the file is replaced atomically
+// so a crash can't corrupt it. The old binary file is moved aside once its entries are migrated.
+func (rs *ReadStatus) Save() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.prune(time.Now())
+	file := readStatusFile{Version: readStatusVersion, Read: rs.read}
+	for hash := range rs.legacy {
+		file.Legacy = append(file.Legacy, hash)
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("cache.Save: %w", err)
+	}
+
+	log.Println("Marshalling the data yielded a size of", len(data))
+	if err = atomicfile.WriteFile(rs.filePath, data, 0600); err != nil {
+		return fmt.Errorf("cache.Save: %w", err)
+	}
+
+	// NOTE: The old binary file is kept as a backup, like every other upgraded file
+	if err = os.Rename(rs.legacyPath, migrate.BackupPath(rs.legacyPath, 1)); err != nil && !os.IsNotExist(err) {
+		log.Println("Couldn't back up the old read status file", err)
 	}
 
+	rs.dirty = false
 	log.Println("Written succesffully")
 	return nil
 }
 
-// MarkAsRead adds an article to the set.
-func (rs *ReadStatus) MarkAsRead(url string) {
-	rs.set[hashArticle(url)] = struct{}{}
+// prune forgets the articles which were read more than DefaultReadStatusAge ago
+func (rs *ReadStatus) prune(now time.Time) {
+	if DefaultReadStatusAge <= 0 {
+		return
+	}
+
+	for feed, articles := range rs.read {
+		for key, readAt := range articles {
+			if now.Sub(readAt) > DefaultReadStatusAge {
+				delete(articles, key)
+			}
+		}
+
+		if len(articles) == 0 {
+			delete(rs.read, feed)
+		}
+	}
 }
 
-// IsRead checks if an article is already in the set.
-func (rs ReadStatus) IsRead(url string) bool {
-	_, ok := rs.set[hashArticle(url)]
-	return ok
+// MarkAsRead marks an article as read now.
+func (rs *ReadStatus) MarkAsRead(id ArticleID) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.read[id.Feed] == nil {
+		rs.read[id.Feed] = make(map[string]time.Time)
+	}
+
+	rs.read[id.Feed][id.Key] = time.Now()
+	rs.dirty = true
 }
 
-// MarkAsUnread removes an article from the set.
-func (rs *ReadStatus) MarkAsUnread(url string) {
-	delete(rs.set, hashArticle(url))
+// IsRead checks if an article was read.
+func (rs *ReadStatus) IsRead(id ArticleID) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	_, ok := rs.read[id.Feed][id.Key]
+	return ok
 }
 
-// marshal converts the set to bytes.
-func marshal(set map[uint32]struct{}) []byte {
-	result := make([]byte, 0, len(set)*4)
-	for k := range set {
-		result = binary.LittleEndian.AppendUint32(result, k)
+// MarkAsUnread forgets that an article was read.
+func (rs *ReadStatus) MarkAsUnread(id ArticleID) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	delete(rs.read[id.Feed], id.Key)
+	if len(rs.read[id.Feed]) == 0 {
+		delete(rs.read, id.Feed)
 	}
-	return result
+
+	rs.dirty = true
 }
 
-// unmarshal converts bytes to a set.
+// unmarshal converts the old binary format to a set of hashes.
 func unmarshal(data []byte) (map[uint32]struct{}, error) {
 	set := make(map[uint32]struct{})
 	if len(data)%4 != 0 {
@@ -116,7 +285,7 @@ func unmarshal(data []byte) (map[uint32]struct{}, error) {
 	return set, nil
 }
 
-// hashArticle hashes the gofeed.Item to a uint32.
+// hashArticle hashes an article link the way the old format did.
 func hashArticle(url string) uint32 {
 	h := murmur3.New32()
 	h.Write([]byte(url))
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/TypicalAM/goread/internal/backend/rss"
+)
+
+// The schemes of the feed sources which are not downloaded over http
+const (
+	fileScheme   = "file://"
+	execScheme   = "exec:"
+	filterScheme = "filter:"
+)
+
+// maxFeedSize is the maximum amount of bytes read from a feed source
+const maxFeedSize = 50 << 20
+
+// commandError is returned when a feed command fails, it doesn't unwrap so
+// that the output of the command isn't lost when the error is shown
+type commandError struct {
+	command string
+	stderr  string
+	err     error
+}
+
+// Error fulfills the error interface
+func (e commandError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("command %q failed: %v", e.command, e.err)
+	}
+
+	return fmt.Sprintf("command %q failed: %v: %s", e.command, e.err, e.stderr)
+}
+
+// isHTTP checks if a feed is downloaded over http, only those feeds support conditional requests and discovery
+func isHTTP(rawURL string) bool {
+	return !strings.HasPrefix(rawURL, fileScheme) &&
+		!strings.HasPrefix(rawURL, execScheme) &&
+		!strings.HasPrefix(rawURL, filterScheme)
+}
+
+// readFeed reads the raw contents of a feed from its source. Besides http urls, a feed can be
+// a local file (file://path), the output of a command (exec:command) or another source piped
+// through a command (filter:source|command). The validators are only used for http sources.
+func (c *Cache) readFeed(ctx context.Context, rawURL string, opts rss.FetchOptions, etag, lastModified string) ([]byte, http.Header, error) {
+	switch {
+	case strings.HasPrefix(rawURL, fileScheme):
+		data, err := readFile(strings.TrimPrefix(rawURL, fileScheme))
+		return data, http.Header{}, err
+
+	case strings.HasPrefix(rawURL, execScheme):
+		data, err := runCommand(ctx, strings.TrimPrefix(rawURL, execScheme), nil)
+		return data, http.Header{}, err
+
+	case strings.HasPrefix(rawURL, filterScheme):
+		source, command, ok := strings.Cut(strings.TrimPrefix(rawURL, filterScheme), "|")
+		if !ok || strings.TrimSpace(command) == "" {
+			return nil, nil, fmt.Errorf("filter %q has no command, the format is filter:source|command", rawURL)
+		}
+
+		data, header, err := c.readFeed(ctx, strings.TrimSpace(source), opts, etag, lastModified)
+		if err != nil {
+			return nil, header, err
+		}
+
+		filtered, err := runCommand(ctx, command, data)
+		return filtered, header, err
+	}
+
+	return c.downloadFeed(ctx, rawURL, opts, etag, lastModified)
+}
+
+// downloadFeed downloads a feed over http, errNotModified is returned if the server confirms
+// that the feed didn't change since the validators were issued
+func (c *Cache) downloadFeed(ctx context.Context, url string, opts rss.FetchOptions, etag, lastModified string) ([]byte, http.Header, error) {
+	req, err := newRequest(ctx, url, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := c.fetcher().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, resp.Header, errNotModified
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, newStatusError(resp)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, resp.Header, nil
+}
+
+// readFile reads a local feed file, a leading ~ is expanded to the home directory
+func readFile(path string) ([]byte, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(home, path[2:])
+	}
+
+	file, err := os.Open(filepath.FromSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, maxFeedSize))
+}
+
+// runCommand runs a command using the system shell and returns its output, the input is passed on stdin
+func runCommand(ctx context.Context, command string, input []byte) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command) // nolint:gosec
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command) // nolint:gosec
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+
+		return nil, commandError{command, strings.TrimSpace(stderr.String()), err}
+	}
+
+	return stdout.Bytes(), nil
+}
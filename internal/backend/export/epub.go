@@ -0,0 +1,269 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// epubFuncs are the helpers available in the epub templates
+var epubFuncs = template.FuncMap{"xml": escapeXML}
+
+// epubContainer points the reader at the package document
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// epubPackage is the package document listing the contents of the book
+var epubPackage = template.Must(template.New("opf").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{.ID}}</dc:identifier>
+    <dc:title>Saved articles</dc:title>
+    <dc:creator>goread</dc:creator>
+    <dc:language>en</dc:language>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+{{- range .Chapters}}
+    <item id="{{.ID}}" href="{{.File}}" media-type="application/xhtml+xml"/>
+{{- end}}
+{{- range .Images}}
+    <item id="{{.ID}}" href="{{xml .File}}" media-type="{{.MediaType}}"/>
+{{- end}}
+  </manifest>
+  <spine>
+    <itemref idref="nav"/>
+{{- range .Chapters}}
+    <itemref idref="{{.ID}}"/>
+{{- end}}
+  </spine>
+</package>
+`))
+
+// epubNav is the table of contents
+var epubNav = template.Must(template.New("nav").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>Saved articles</title></head>
+<body>
+<nav epub:type="toc" id="toc">
+<h1>Saved articles</h1>
+<ol>
+{{- range .}}
+<li><a href="{{.File}}">{{xml .Title}}</a></li>
+{{- end}}
+</ol>
+</nav>
+</body>
+</html>
+`))
+
+// epubChapter is a single article
+var epubChapter = template.Must(template.New("chapter").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head><title>{{xml .Title}}</title></head>
+<body>
+{{- if .Link}}
+<p><a href="{{xml .Link}}">{{xml .Feed}}</a></p>
+{{- end}}
+{{- if .Tags}}
+<p>{{range .Tags}}#{{xml .}} {{end}}</p>
+{{- end}}
+{{- if .Note}}
+<blockquote><p>{{xml .Note}}</p></blockquote>
+{{- end}}
+{{.Body}}
+</body>
+</html>
+`))
+
+// epubChapterData is a single article in the epub templates
+type epubChapterData struct {
+	ID    string
+	File  string
+	Title string
+	Feed  string
+	Link  string
+	Tags  []string
+	Note  string
+	Body  string
+}
+
+// epubFile is a file of the epub rendered from a template
+type epubFile struct {
+	name     string
+	template *template.Template
+	data     interface{}
+}
+
+// epubImage is a saved image bundled into the epub
+type epubImage struct {
+	ID        string
+	File      string
+	MediaType string
+	data      []byte
+}
+
+// writeEPUB writes the articles into an EPUB 3 bundle, every article is a chapter. The saved images are
+// bundled along with the articles, remote images are left out since e-readers are often offline.
+func writeEPUB(articles []Article, path string, now time.Time) error {
+	chapters := make([]epubChapterData, len(articles))
+	var images []epubImage
+	bundled := make(map[string]string)
+
+	for i, article := range articles {
+		body, err := article.renderHTML(func(src string) string {
+			local, ok := localFile(src)
+			if !ok {
+				return src
+			}
+
+			if file, ok := bundled[local]; ok {
+				return file
+			}
+
+			data, err := os.ReadFile(local)
+			if err != nil {
+				return src
+			}
+
+			mediaType := mime.TypeByExtension(filepath.Ext(local))
+			if !strings.HasPrefix(mediaType, "image/") {
+				return src
+			}
+
+			image := epubImage{
+				ID:        fmt.Sprintf("image-%d", len(images)+1),
+				File:      "images/" + filepath.Base(local),
+				MediaType: strings.Split(mediaType, ";")[0],
+				data:      data,
+			}
+
+			images = append(images, image)
+			bundled[local] = image.File
+			return image.File
+		})
+		if err != nil {
+			return err
+		}
+
+		feed := article.FeedName
+		if feed == "" {
+			feed = "Original article"
+		}
+
+		chapters[i] = epubChapterData{
+			ID:    fmt.Sprintf("chapter-%d", i+1),
+			File:  fmt.Sprintf("chapter-%d.xhtml", i+1),
+			Title: article.title(),
+			Feed:  feed,
+			Link:  article.Item.Link,
+			Tags:  article.Tags,
+			Note:  article.Note,
+			Body:  body,
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	// NOTE: The mimetype has to be the first file and it must not be compressed
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+
+	if _, err = mimetype.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+
+	files := []epubFile{
+		{"OEBPS/content.opf", epubPackage, map[string]interface{}{
+			"ID":       bookID(articles),
+			"Modified": now.UTC().Format("2006-01-02T15:04:05Z"),
+			"Chapters": chapters,
+			"Images":   images,
+		}},
+		{"OEBPS/nav.xhtml", epubNav, chapters},
+	}
+
+	for _, chapter := range chapters {
+		files = append(files, epubFile{"OEBPS/" + chapter.File, epubChapter, chapter})
+	}
+
+	if err = writeZipFile(zw, "META-INF/container.xml", []byte(epubContainer)); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		var content bytes.Buffer
+		if err = file.template.Execute(&content, file.data); err != nil {
+			return err
+		}
+
+		if err = writeZipFile(zw, file.name, content.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range images {
+		if err = writeZipFile(zw, "OEBPS/"+image.File, image.data); err != nil {
+			return err
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644) //nolint:gosec
+}
+
+// writeZipFile writes a single compressed file into the archive
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// bookID returns an identifier of the book which stays the same as long as the same articles are exported
+func bookID(articles []Article) string {
+	h := sha256.New()
+	for _, article := range articles {
+		fmt.Fprintln(h, article.FeedURL, article.Item.GUID, article.Item.Link, article.Item.Title)
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	return fmt.Sprintf("urn:uuid:%s-%s-%s-%s-%s", sum[:8], sum[8:12], sum[12:16], sum[16:20], sum[20:32])
+}
+
+// escapeXML escapes the text so that it can be used in xml content and attributes
+func escapeXML(text string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
@@ -0,0 +1,236 @@
+// Package export writes the saved articles out of goread, so that they can be read elsewhere.
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// Format is a format the articles can be exported to
+type Format string
+
+const (
+	// Markdown writes one markdown file per article into a directory
+	Markdown Format = "markdown"
+
+	// HTML writes a single self-contained html file
+	HTML Format = "html"
+
+	// EPUB writes an EPUB bundle for e-readers
+	EPUB Format = "epub"
+)
+
+// ErrUnknownFormat is returned when the export format is not supported
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// markdownImagePattern matches the images in markdown, the first group is the image url
+var markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)`)
+
+// ParseFormat parses the name of an export format
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "md", "markdown":
+		return Markdown, nil
+
+	case "html", "htm":
+		return HTML, nil
+
+	case "epub":
+		return EPUB, nil
+	}
+
+	return "", fmt.Errorf("export.ParseFormat: %w: %q", ErrUnknownFormat, name)
+}
+
+// DefaultPath returns the default output path for a format, markdown is written into a directory
+func (f Format) DefaultPath() string {
+	switch f {
+	case HTML:
+		return "goread-saved.html"
+
+	case EPUB:
+		return "goread-saved.epub"
+	}
+
+	return "goread-saved"
+}
+
+// Article is a single article to be exported
+type Article struct {
+	Item     *gofeed.Item
+	FeedName string
+	FeedURL  string
+	Markdown string
+	Tags     []string
+	Note     string
+}
+
+// Filter decides which articles are exported, the zero value lets every article through
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	Feeds []string
+	Tags  []string
+}
+
+// Matches checks if the article passes the filter. The feeds are matched by their name or their url and
+// the article needs to have any of the tags. When a date range is given, articles without a publishing
+// date are left out.
+func (f Filter) Matches(article Article) bool {
+	if len(f.Feeds) != 0 {
+		found := false
+		for _, feed := range f.Feeds {
+			if feed == article.FeedName || feed == article.FeedURL {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Tags) != 0 && !article.hasAnyTag(f.Tags) {
+		return false
+	}
+
+	if f.Since.IsZero() && f.Until.IsZero() {
+		return true
+	}
+
+	published := article.Item.PublishedParsed
+	if published == nil {
+		return false
+	}
+
+	if !f.Since.IsZero() && published.Before(f.Since) {
+		return false
+	}
+
+	return f.Until.IsZero() || published.Before(f.Until)
+}
+
+// Write exports the articles to the path in the given format
+func Write(articles []Article, format Format, path string) error {
+	var err error
+	switch format {
+	case Markdown:
+		err = writeMarkdown(articles, path)
+
+	case HTML:
+		err = writeHTML(articles, path)
+
+	case EPUB:
+		err = writeEPUB(articles, path, time.Now())
+
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("export.Write: %w", err)
+	}
+
+	return nil
+}
+
+// title returns the title of the article, falling back to its link
+func (a Article) title() string {
+	if title := strings.TrimSpace(a.Item.Title); title != "" {
+		return title
+	}
+
+	if a.Item.Link != "" {
+		return a.Item.Link
+	}
+
+	return "Untitled"
+}
+
+// hasAnyTag checks if the article has any of the tags, the tags are matched case-insensitively
+func (a Article) hasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		for _, candidate := range a.Tags {
+			if strings.EqualFold(candidate, tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// renderHTML converts the markdown of the article to html, the images are passed through the rewrite
+// function which returns their new source. The result is valid XHTML, so that it can be used in an EPUB.
+func (a Article) renderHTML(rewrite func(src string) string) (string, error) {
+	var buf bytes.Buffer
+	renderer := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(html.WithXHTML()),
+	)
+
+	// NOTE: The images are rewritten before rendering, since goldmark drops the file urls of the saved images
+	if err := renderer.Convert([]byte(localizeImages(a.Markdown, rewrite)), &buf); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// localFile returns the path of an image saved along with the article, remote images are not local files
+func localFile(src string) (string, bool) {
+	parsed, err := url.Parse(src)
+	if err != nil || parsed.Scheme != "file" || parsed.Path == "" {
+		return "", false
+	}
+
+	path := parsed.Path
+	// NOTE: Windows file urls have a slash before the drive letter
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path), true
+}
+
+// slugify turns a title into a short file name friendly string
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+
+		case !dash && b.Len() != 0:
+			b.WriteRune('-')
+			dash = true
+		}
+
+		if b.Len() >= 60 {
+			break
+		}
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		return "article"
+	}
+
+	return slug
+}
@@ -0,0 +1,220 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// testArticles returns two articles from different feeds, the first one has a saved image
+func testArticles(t *testing.T) []Article {
+	image := filepath.Join(t.TempDir(), "0123abcd.png")
+	if err := os.WriteFile(image, []byte("\x89PNG image"), 0600); err != nil {
+		t.Fatalf("couldn't write the image: %v", err)
+	}
+
+	imageURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(image)}).String()
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	return []Article{
+		{
+			Item: &gofeed.Item{
+				Title:           "First & <best> article",
+				Link:            "https://example.com/first?a=1&b=2",
+				PublishedParsed: &first,
+				Authors:         []*gofeed.Person{{Name: "Jane"}},
+			},
+			FeedName: "Example",
+			FeedURL:  "https://example.com/feed.xml",
+			Tags:     []string{"Research", "go"},
+			Note:     "Read this again",
+			Markdown: "# First & <best> article\n\nSome **text** with ![a picture](" + imageURL + ")\n",
+		},
+		{
+			Item: &gofeed.Item{
+				Title:           "Second article",
+				Link:            "https://other.com/second",
+				PublishedParsed: &second,
+			},
+			FeedName: "Other",
+			FeedURL:  "https://other.com/feed.xml",
+			Markdown: "# Second article\n\n![remote](https://other.com/image.png)\n",
+		},
+	}
+}
+
+// TestFilter if we get an error then the articles are not filtered by their feed, their tags or their date
+func TestFilter(t *testing.T) {
+	articles := testArticles(t)
+	undated := Article{Item: &gofeed.Item{Title: "Undated"}, FeedName: "Example"}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []bool
+	}{
+		{"no filter", Filter{}, []bool{true, true, true}},
+		{"feed name", Filter{Feeds: []string{"Example"}}, []bool{true, false, true}},
+		{"feed url", Filter{Feeds: []string{"https://other.com/feed.xml"}}, []bool{false, true, false}},
+		{"since", Filter{Since: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}, []bool{false, true, false}},
+		{"until", Filter{Until: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}, []bool{true, false, false}},
+		{"tag", Filter{Tags: []string{"#research", "rust"}}, []bool{true, false, false}},
+	}
+
+	for _, test := range tests {
+		for i, article := range append(articles, undated) {
+			if got := test.filter.Matches(article); got != test.expected[i] {
+				t.Errorf("%s: expected article %d to match %v, got %v", test.name, i, test.expected[i], got)
+			}
+		}
+	}
+}
+
+// TestParseFormat if we get an error then the format names are not recognized
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"md": Markdown, "Markdown": Markdown, "html": HTML, "EPUB": EPUB} {
+		if format, err := ParseFormat(name); err != nil || format != expected {
+			t.Errorf("expected %q to parse as %q, got %q (%v)", name, expected, format, err)
+		}
+	}
+
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// TestWriteMarkdown if we get an error then the markdown files are missing their front matter or images
+func TestWriteMarkdown(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "export")
+	if err := Write(testArticles(t), Markdown, dir); err != nil {
+		t.Fatalf("couldn't export: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "2024-03-01-first-best-article.md"))
+	if err != nil {
+		t.Fatalf("couldn't read the exported article: %v", err)
+	}
+
+	content := string(data)
+	for _, expected := range []string{
+		"---\ntitle: First & <best> article\n",
+		"feed: Example\n",
+		"authors:\n    - Jane\n",
+		"published: 2024-03-01T12:00:00Z\n",
+		"tags:\n    - Research\n    - go\n",
+		"note: Read this again\n",
+		"![a picture](images/0123abcd.png)",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected the article to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "images", "0123abcd.png")); err != nil {
+		t.Errorf("expected the image to be copied: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "2024-05-01-second-article.md")); err != nil {
+		t.Errorf("expected the second article to be exported: %v", err)
+	}
+}
+
+// TestWriteHTML if we get an error then the html file isn't self-contained
+func TestWriteHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saved.html")
+	if err := Write(testArticles(t), HTML, path); err != nil {
+		t.Fatalf("couldn't export: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("couldn't read the export: %v", err)
+	}
+
+	content := string(data)
+	for _, expected := range []string{
+		`<a href="#article-1">First &amp; &lt;best&gt; article</a>`,
+		`src="data:image/png;base64,`,
+		`src="https://other.com/image.png"`,
+		`<strong>text</strong>`,
+		` #Research #go</p>`,
+		`<blockquote class="note">Read this again</blockquote>`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected the export to contain %q", expected)
+		}
+	}
+
+	if strings.Contains(content, "file://") {
+		t.Error("expected the saved image to be embedded")
+	}
+}
+
+// TestWriteEPUB if we get an error then the epub bundle is malformed
+func TestWriteEPUB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saved.epub")
+	if err := Write(testArticles(t), EPUB, path); err != nil {
+		t.Fatalf("couldn't export: %v", err)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("couldn't open the epub: %v", err)
+	}
+	defer archive.Close()
+
+	if first := archive.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Fatalf("expected an uncompressed mimetype as the first file, got %q", first.Name)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	for _, name := range []string{
+		"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml",
+		"OEBPS/chapter-1.xhtml", "OEBPS/chapter-2.xhtml", "OEBPS/images/0123abcd.png",
+	} {
+		file, ok := files[name]
+		if !ok {
+			t.Errorf("expected %s in the epub", name)
+			continue
+		}
+
+		if !strings.HasSuffix(name, ".png") {
+			assertWellFormed(t, file)
+		}
+	}
+}
+
+// assertWellFormed fails the test if the file isn't well formed xml
+func assertWellFormed(t *testing.T, file *zip.File) {
+	t.Helper()
+	r, err := file.Open()
+	if err != nil {
+		t.Fatalf("couldn't open %s: %v", file.Name, err)
+	}
+	defer r.Close()
+
+	decoder := xml.NewDecoder(r)
+	decoder.Entity = xml.HTMLEntity
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err != io.EOF {
+				t.Errorf("%s is not well formed: %v", file.Name, err)
+			}
+
+			return
+		}
+	}
+}
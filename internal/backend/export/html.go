@@ -0,0 +1,112 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// htmlTemplate is the layout of the exported html file
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Saved articles</title>
+<style>
+body { max-width: 48em; margin: 0 auto; padding: 1em; font-family: sans-serif; line-height: 1.5; }
+img { max-width: 100%; height: auto; }
+pre { overflow-x: auto; }
+article { border-top: 1px solid #ccc; margin-top: 2em; }
+.meta { color: #666; font-size: 0.9em; }
+.note { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Saved articles</h1>
+<nav>
+<ol>
+{{- range .}}
+<li><a href="#{{.ID}}">{{.Title}}</a></li>
+{{- end}}
+</ol>
+</nav>
+{{- range .}}
+<article id="{{.ID}}">
+<p class="meta">{{if .Feed}}{{.Feed}}{{end}}{{if .Link}} · <a href="{{.Link}}">original</a>{{end}}{{range .Tags}} #{{.}}{{end}}</p>
+{{- if .Note}}
+<blockquote class="note">{{.Note}}</blockquote>
+{{- end}}
+{{.Body}}
+</article>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlArticle is a single article in the html template
+type htmlArticle struct {
+	ID    string
+	Title string
+	Feed  string
+	Link  string
+	Tags  []string
+	Note  string
+	Body  template.HTML
+}
+
+// writeHTML writes all the articles into a single html file, the saved images are embedded into it
+func writeHTML(articles []Article, path string) error {
+	data := make([]htmlArticle, len(articles))
+	for i, article := range articles {
+		body, err := article.renderHTML(embedImage)
+		if err != nil {
+			return err
+		}
+
+		data[i] = htmlArticle{
+			ID:    "article-" + strconv.Itoa(i+1),
+			Title: article.title(),
+			Feed:  article.FeedName,
+			Link:  article.Item.Link,
+			Tags:  article.Tags,
+			Note:  article.Note,
+			Body:  template.HTML(body), //nolint:gosec
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644) //nolint:gosec
+}
+
+// embedImage turns a saved image into a data url, remote images are left as they are
+func embedImage(src string) string {
+	path, ok := localFile(src)
+	if !ok {
+		return src
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return src
+	}
+
+	mediaType := mime.TypeByExtension(filepath.Ext(path))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter is the metadata written at the top of every exported markdown file
+type frontMatter struct {
+	Title      string     `yaml:"title"`
+	Link       string     `yaml:"link,omitempty"`
+	Feed       string     `yaml:"feed,omitempty"`
+	FeedURL    string     `yaml:"feed_url,omitempty"`
+	Authors    []string   `yaml:"authors,omitempty"`
+	Published  *time.Time `yaml:"published,omitempty"`
+	Updated    *time.Time `yaml:"updated,omitempty"`
+	GUID       string     `yaml:"guid,omitempty"`
+	Categories []string   `yaml:"categories,omitempty"`
+	Tags       []string   `yaml:"tags,omitempty"`
+	Note       string     `yaml:"note,omitempty"`
+}
+
+// writeMarkdown writes every article into its own markdown file in the directory, the saved images
+// are copied into an images directory next to them so that the directory can be moved around
+func writeMarkdown(articles []Article, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	imageDir := filepath.Join(dir, "images")
+	names := make(map[string]struct{}, len(articles))
+	for _, article := range articles {
+		content, err := markdownFile(article)
+		if err != nil {
+			return err
+		}
+
+		content = localizeImages(content, func(src string) string {
+			path, ok := localFile(src)
+			if !ok {
+				return src
+			}
+
+			if err := copyFile(path, filepath.Join(imageDir, filepath.Base(path))); err != nil {
+				return src
+			}
+
+			return "images/" + filepath.Base(path)
+		})
+
+		name := uniqueName(names, markdownName(article), ".md")
+		if err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil { //nolint:gosec
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markdownFile returns the contents of the markdown file of an article along with its front matter
+func markdownFile(article Article) (string, error) {
+	item := article.Item
+	meta := frontMatter{
+		Title:      article.title(),
+		Link:       item.Link,
+		Feed:       article.FeedName,
+		FeedURL:    article.FeedURL,
+		Published:  item.PublishedParsed,
+		Updated:    item.UpdatedParsed,
+		GUID:       item.GUID,
+		Categories: item.Categories,
+		Tags:       article.Tags,
+		Note:       article.Note,
+	}
+
+	for _, author := range item.Authors {
+		if author != nil && author.Name != "" {
+			meta.Authors = append(meta.Authors, author.Name)
+		}
+	}
+
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("front matter of %q: %w", meta.Title, err)
+	}
+
+	return "---\n" + string(data) + "---\n\n" + article.Markdown, nil
+}
+
+// markdownName returns the file name of an article, prefixed with its publishing date so that the files sort nicely
+func markdownName(article Article) string {
+	name := slugify(article.title())
+	if published := article.Item.PublishedParsed; published != nil {
+		name = published.Format("2006-01-02") + "-" + name
+	}
+
+	return name
+}
+
+// uniqueName appends a number to the name if it is already taken
+func uniqueName(taken map[string]struct{}, name, ext string) string {
+	candidate := name + ext
+	for i := 2; ; i++ {
+		if _, ok := taken[candidate]; !ok {
+			taken[candidate] = struct{}{}
+			return candidate
+		}
+
+		candidate = fmt.Sprintf("%s-%d%s", name, i, ext)
+	}
+}
+
+// localizeImages replaces the image urls in the markdown using the rewrite function
+func localizeImages(content string, rewrite func(string) string) string {
+	return markdownImagePattern.ReplaceAllStringFunc(content, func(image string) string {
+		match := markdownImagePattern.FindStringSubmatch(image)
+		return strings.TrimSuffix(image, match[1]) + rewrite(match[1])
+	})
+}
+
+// copyFile copies a file, creating the directory of the destination if needed
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, data, 0644) //nolint:gosec
+}
@@ -0,0 +1,318 @@
+// Package filter implements the rules which decide which articles of a feed are shown. A rule starts with
+// an action - "hide" or "show" - followed by conditions combined with "and", "or", "not" and parentheses:
+//
+//	hide title:sponsored or author:"Marketing team"
+//	show (category:/^go(lang)?$/ or title:go) and not link:reddit.com
+//
+// A condition is a pattern optionally prefixed by the field it is matched against - title, author,
+// category (or tag), link or content. Without a field the pattern is matched against the title and the
+// content. The patterns are matched as case-insensitive substrings, a pattern between slashes is a
+// regular expression (which is also case-insensitive, unless it turns that off with "(?-i)").
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// ErrInvalidRule is returned when a rule can't be parsed
+var ErrInvalidRule = errors.New("invalid filter rule")
+
+// RuleError describes why a rule can't be parsed, it matches ErrInvalidRule
+type RuleError struct {
+	Rule   string
+	Reason string
+}
+
+// Error returns the rule along with the reason
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%s %q: %s", ErrInvalidRule, e.Rule, e.Reason)
+}
+
+// Is makes the error match ErrInvalidRule
+func (e *RuleError) Is(target error) bool {
+	return target == ErrInvalidRule
+}
+
+// Action is what happens to the articles matching a rule
+type Action int
+
+const (
+	// Hide hides the matching articles
+	Hide Action = iota
+
+	// Show only shows the matching articles, an article has to match one of the show rules of every scope
+	Show
+)
+
+// Field is the part of an article a condition is matched against
+type Field string
+
+const (
+	// Any matches the title and the content of the article
+	Any Field = ""
+
+	// Title matches the title of the article
+	Title Field = "title"
+
+	// Author matches the names and the emails of the authors
+	Author Field = "author"
+
+	// Category matches each of the categories (tags) of the article
+	Category Field = "category"
+
+	// Link matches the links of the article
+	Link Field = "link"
+
+	// Content matches the description and the content of the article
+	Content Field = "content"
+)
+
+// fields are the field names which can prefix a pattern, "tag" is an alias of "category"
+var fields = map[string]Field{
+	"title":    Title,
+	"author":   Author,
+	"category": Category,
+	"tag":      Category,
+	"link":     Link,
+	"content":  Content,
+}
+
+// Rule is a single parsed filter rule
+type Rule struct {
+	Action Action
+	source string
+	expr   node
+}
+
+// Parse parses a rule, like "hide title:sponsored"
+func Parse(rule string) (*Rule, error) {
+	p := parser{}
+	if err := p.tokenize(rule); err != nil {
+		return nil, fmt.Errorf("filter.Parse: %w", &RuleError{rule, err.Error()})
+	}
+
+	action, ok := p.action()
+	if !ok {
+		return nil, fmt.Errorf("filter.Parse: %w", &RuleError{rule, "it has to start with \"hide\" or \"show\""})
+	}
+
+	expr, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("filter.Parse: %w", &RuleError{rule, err.Error()})
+	}
+
+	return &Rule{Action: action, source: strings.TrimSpace(rule), expr: expr}, nil
+}
+
+// Keywords returns a rule matching the articles which include any of the words in their title or content,
+// it is the rule equivalent to the whitelist and blacklist keyword lists
+func Keywords(action Action, words []string) *Rule {
+	var expr node
+	for _, word := range words {
+		cond := &condition{field: Any, substring: strings.ToLower(word)}
+		if expr == nil {
+			expr = cond
+		} else {
+			expr = &orNode{expr, cond}
+		}
+	}
+
+	if expr == nil {
+		return nil
+	}
+
+	return &Rule{Action: action, source: action.String() + " " + strings.Join(words, " or "), expr: expr}
+}
+
+// Match checks if the article matches the conditions of the rule
+func (r *Rule) Match(item *gofeed.Item) bool {
+	return r.expr.match(item)
+}
+
+// String returns the source of the rule
+func (r *Rule) String() string {
+	return r.source
+}
+
+// String returns the keyword of the action
+func (a Action) String() string {
+	if a == Show {
+		return "show"
+	}
+
+	return "hide"
+}
+
+// Filter is a set of rules applied to the articles of a feed. The show rules are grouped by the scope they
+// come from, like the global rules and the rules of a feed, so that each scope can narrow the articles down.
+type Filter struct {
+	hide []*Rule
+	show [][]*Rule
+}
+
+// New parses the rules into a filter with a single scope, see NewScoped.
+func New(rules []string) (*Filter, error) {
+	return NewScoped([][]string{rules})
+}
+
+// NewScoped parses the rules of every scope into a filter. The invalid rules are left out of the filter, the
+// first of the errors is returned along with the filter made of the valid ones.
+func NewScoped(scopes [][]string) (*Filter, error) {
+	f := &Filter{}
+	var firstErr error
+	for _, scope := range scopes {
+		var show []*Rule
+		for _, source := range scope {
+			rule, err := Parse(source)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				continue
+			}
+
+			if rule.Action == Show {
+				show = append(show, rule)
+			} else {
+				f.hide = append(f.hide, rule)
+			}
+		}
+
+		if len(show) > 0 {
+			f.show = append(f.show, show)
+		}
+	}
+
+	return f, firstErr
+}
+
+// Add adds a rule to the filter as a scope of its own, nil rules are ignored
+func (f *Filter) Add(rule *Rule) {
+	if rule == nil {
+		return
+	}
+
+	if rule.Action == Show {
+		f.show = append(f.show, []*Rule{rule})
+	} else {
+		f.hide = append(f.hide, rule)
+	}
+}
+
+// Empty checks if the filter has no rules, an empty filter allows every article
+func (f *Filter) Empty() bool {
+	return len(f.hide) == 0 && len(f.show) == 0
+}
+
+// Allows checks if the article should be shown - it can't match any of the hide rules, and it has to
+// match one of the show rules of every scope which has any
+func (f *Filter) Allows(item *gofeed.Item) bool {
+	for _, rule := range f.hide {
+		if rule.Match(item) {
+			return false
+		}
+	}
+
+	for _, scope := range f.show {
+		if !matchesAny(scope, item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesAny checks if the article matches any of the rules
+func matchesAny(rules []*Rule, item *gofeed.Item) bool {
+	for _, rule := range rules {
+		if rule.Match(item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// node is a part of the expression of a rule
+type node interface {
+	match(item *gofeed.Item) bool
+}
+
+// andNode matches when both of its sides match
+type andNode struct{ left, right node }
+
+func (n *andNode) match(item *gofeed.Item) bool { return n.left.match(item) && n.right.match(item) }
+
+// orNode matches when either of its sides matches
+type orNode struct{ left, right node }
+
+func (n *orNode) match(item *gofeed.Item) bool { return n.left.match(item) || n.right.match(item) }
+
+// notNode matches when the node inside of it doesn't
+type notNode struct{ inner node }
+
+func (n *notNode) match(item *gofeed.Item) bool { return !n.inner.match(item) }
+
+// condition matches a pattern against a field of the article
+type condition struct {
+	field     Field
+	substring string
+	regex     *regexp.Regexp
+}
+
+func (c *condition) match(item *gofeed.Item) bool {
+	for _, value := range values(c.field, item) {
+		if c.regex != nil && c.regex.MatchString(value) {
+			return true
+		}
+
+		if c.regex == nil && strings.Contains(strings.ToLower(value), c.substring) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// values returns the parts of the article a field refers to
+func values(field Field, item *gofeed.Item) []string {
+	switch field {
+	case Title:
+		return []string{item.Title}
+
+	case Author:
+		var result []string
+		if item.Author != nil {
+			result = append(result, item.Author.Name, item.Author.Email)
+		}
+
+		for _, author := range item.Authors {
+			if author != nil {
+				result = append(result, author.Name, author.Email)
+			}
+		}
+
+		return result
+
+	case Category:
+		return item.Categories
+
+	case Link:
+		return append([]string{item.Link}, item.Links...)
+
+	case Content:
+		return []string{item.Description, item.Content}
+	}
+
+	return []string{item.Title, item.Description, item.Content}
+}
@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// testItem is the article the rules are matched against
+var testItem = &gofeed.Item{
+	Title:       "Go 1.22 is released",
+	Description: "The latest Go release brings range over integers",
+	Content:     "<p>Sponsored by Example Corp</p>",
+	Link:        "https://go.dev/blog/go1.22",
+	Authors:     []*gofeed.Person{{Name: "Eli Bendersky", Email: "eli@example.com"}},
+	Categories:  []string{"golang", "Release"},
+}
+
+// TestFilterMatch if we get an error then a rule doesn't match the article like it should
+func TestFilterMatch(t *testing.T) {
+	tests := map[string]bool{
+		"hide go":                                         true,
+		"hide rust":                                       false,
+		"hide title:sponsored":                            false,
+		"hide content:SPONSORED":                          true,
+		"hide sponsored":                                  true,
+		"hide author:bendersky":                           true,
+		"hide author:eli@example.com":                     true,
+		"hide tag:release":                                true,
+		"hide category:/^go$/":                            false,
+		"hide category:/^go(lang)?$/":                     true,
+		"hide link:go.dev/blog":                           true,
+		"hide title:\"is released\"":                      true,
+		"hide title:\"is \\\"released\\\"\"":              false,
+		"hide title:/^go \\d+\\.\\d+/":                    true,
+		"hide title:/(?-i)^GO/":                           false,
+		"hide title:go and author:pike":                   false,
+		"hide title:go or author:pike":                    true,
+		"hide not author:pike":                            true,
+		"hide NOT (title:go or author:pike)":              false,
+		"hide title:rust or title:go and not link:reddit": true,
+		"show (title:rust or title:go) and tag:golang":    true,
+		"hide https://go.dev/blog/go1.22":                 false,
+	}
+
+	for source, expected := range tests {
+		rule, err := Parse(source)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", source, err)
+			continue
+		}
+
+		if rule.Match(testItem) != expected {
+			t.Errorf("expected %q to match: %t", source, expected)
+		}
+	}
+}
+
+// TestFilterParseErrors if we get an error then an invalid rule is accepted
+func TestFilterParseErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		"title:go",
+		"remove title:go",
+		"hide",
+		"hide title:",
+		"hide title:\"unterminated",
+		"hide title:/unterminated",
+		"hide title:/[a-/",
+		"hide (title:go",
+		"hide title:go)",
+		"hide title:go and",
+		"hide or title:go",
+		"hide not",
+	}
+
+	for _, source := range invalid {
+		if _, err := Parse(source); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("expected ErrInvalidRule for %q, got %v", source, err)
+		}
+	}
+}
+
+// TestFilterAllows if we get an error then the hide and show rules aren't combined properly
+func TestFilterAllows(t *testing.T) {
+	f, err := New([]string{"hide author:pike", "show tag:rust", "show title:go"})
+	if err != nil {
+		t.Fatalf("failed to create the filter: %v", err)
+	}
+
+	if !f.Allows(testItem) {
+		t.Errorf("expected the article matching a show rule to be allowed")
+	}
+
+	f.Add(Keywords(Hide, []string{"example corp", "nothing"}))
+	if f.Allows(testItem) {
+		t.Errorf("expected the article matching a hide rule to be hidden")
+	}
+
+	f, err = New([]string{"show tag:rust", "not a rule"})
+	if err == nil {
+		t.Errorf("expected an error for the invalid rule")
+	}
+
+	if f.Allows(testItem) {
+		t.Errorf("expected the article not matching any show rule to be hidden")
+	}
+
+	if f, _ = New(nil); !f.Empty() || !f.Allows(testItem) {
+		t.Errorf("expected an empty filter to allow everything")
+	}
+
+	if Keywords(Show, nil) != nil {
+		t.Errorf("expected no rule for an empty keyword list")
+	}
+}
+
+// TestFilterScopes if we get an error then the show rules of the scopes aren't all required to match
+func TestFilterScopes(t *testing.T) {
+	f, err := NewScoped([][]string{{"show tag:go", "show tag:rust"}, nil, {"show title:go"}})
+	if err != nil {
+		t.Fatalf("failed to create the filter: %v", err)
+	}
+
+	if !f.Allows(testItem) {
+		t.Errorf("expected the article matching a show rule of every scope to be allowed")
+	}
+
+	f, _ = NewScoped([][]string{{"show tag:go"}, {"show title:rust", "show author:nobody"}})
+	if f.Allows(testItem) {
+		t.Errorf("expected the article not matching the show rules of a scope to be hidden")
+	}
+
+	f, _ = New([]string{"show tag:go"})
+	f.Add(Keywords(Show, []string{"nothing"}))
+	if f.Allows(testItem) {
+		t.Errorf("expected an added show rule to be a scope of its own")
+	}
+}
@@ -0,0 +1,270 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a token in a rule
+type tokenKind int
+
+const (
+	tokenCondition tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// token is a single part of a rule
+type token struct {
+	kind tokenKind
+	cond *condition
+	text string
+}
+
+// String returns the text of the token for the error messages
+func (t token) String() string {
+	return fmt.Sprintf("%q", t.text)
+}
+
+// parser is a recursive descent parser of the rules, "not" binds tighter than "and", which binds tighter than "or"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// tokenize splits the rule into tokens
+func (p *parser) tokenize(rule string) error {
+	runes := []rune(rule)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			p.tokens = append(p.tokens, token{kind: tokenOpen, text: "("})
+			i++
+
+		case r == ')':
+			p.tokens = append(p.tokens, token{kind: tokenClose, text: ")"})
+			i++
+
+		default:
+			tok, next, err := readCondition(runes, i)
+			if err != nil {
+				return err
+			}
+
+			p.tokens = append(p.tokens, tok)
+			i = next
+		}
+	}
+
+	return nil
+}
+
+// readCondition reads a condition starting at the given position, the bare "and", "or" and "not" words
+// are returned as operators
+func readCondition(runes []rune, start int) (token, int, error) {
+	i, field := start, Any
+	if colon := fieldPrefix(runes[start:]); colon > 0 {
+		if f, ok := fields[strings.ToLower(string(runes[start:start+colon]))]; ok {
+			field, i = f, start+colon+1
+		}
+	}
+
+	if i >= len(runes) || unicode.IsSpace(runes[i]) || runes[i] == '(' || runes[i] == ')' {
+		return token{}, 0, fmt.Errorf("%q has no pattern", string(runes[start:i]))
+	}
+
+	switch runes[i] {
+	case '"':
+		text, next, err := readDelimited(runes, i, '"')
+		if err != nil {
+			return token{}, 0, err
+		}
+
+		cond := &condition{field: field, substring: strings.ToLower(text)}
+		return token{kind: tokenCondition, cond: cond, text: string(runes[start:next])}, next, nil
+
+	case '/':
+		text, next, err := readDelimited(runes, i, '/')
+		if err != nil {
+			return token{}, 0, err
+		}
+
+		regex, err := regexp.Compile("(?i)" + text)
+		if err != nil {
+			return token{}, 0, err
+		}
+
+		cond := &condition{field: field, regex: regex}
+		return token{kind: tokenCondition, cond: cond, text: string(runes[start:next])}, next, nil
+	}
+
+	next := i
+	for next < len(runes) && !unicode.IsSpace(runes[next]) && runes[next] != '(' && runes[next] != ')' {
+		next++
+	}
+
+	text := string(runes[i:next])
+	if field == Any {
+		switch strings.ToLower(text) {
+		case "and":
+			return token{kind: tokenAnd, text: text}, next, nil
+
+		case "or":
+			return token{kind: tokenOr, text: text}, next, nil
+
+		case "not":
+			return token{kind: tokenNot, text: text}, next, nil
+		}
+	}
+
+	cond := &condition{field: field, substring: strings.ToLower(text)}
+	return token{kind: tokenCondition, cond: cond, text: string(runes[start:next])}, next, nil
+}
+
+// readDelimited reads the text between two delimiters, a backslash escapes the delimiter. In a regular
+// expression the other escapes are kept for the regexp package.
+func readDelimited(runes []rune, start int, delim rune) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == delim || (delim == '"' && runes[i+1] == '\\')):
+			b.WriteRune(runes[i+1])
+			i++
+
+		case runes[i] == delim:
+			return b.String(), i + 1, nil
+
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("missing the closing %c", delim)
+}
+
+// fieldPrefix returns the length of the field name before a colon, -1 if the text doesn't start with one
+func fieldPrefix(runes []rune) int {
+	for i, r := range runes {
+		if r == ':' {
+			return i
+		}
+
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+	}
+
+	return -1
+}
+
+// action reads the action the rule starts with
+func (p *parser) action() (Action, bool) {
+	if len(p.tokens) == 0 || p.tokens[0].kind != tokenCondition {
+		return Hide, false
+	}
+
+	p.pos++
+	switch strings.ToLower(p.tokens[0].text) {
+	case "hide":
+		return Hide, true
+
+	case "show":
+		return Show, true
+	}
+
+	return Hide, false
+}
+
+// parseOr parses conditions separated by "or"
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &orNode{left, right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses conditions separated by "and"
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenAnd) {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &andNode{left, right}
+	}
+
+	return left, nil
+}
+
+// parseNot parses a negated condition, a condition or an expression in parentheses
+func (p *parser) parseNot() (node, error) {
+	if p.accept(tokenNot) {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{inner}, nil
+	}
+
+	if p.accept(tokenOpen) {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(tokenClose) {
+			return nil, errors.New("missing the closing )")
+		}
+
+		return inner, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("missing a condition")
+	}
+
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenCondition {
+		return nil, fmt.Errorf("unexpected %s", tok)
+	}
+
+	p.pos++
+	return tok.cond, nil
+}
+
+// accept consumes the next token if it is of the given kind
+func (p *parser) accept(kind tokenKind) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+
+	return false
+}
@@ -1,6 +1,9 @@
 package backend
 
 import (
+	"strings"
+
+	"github.com/TypicalAM/goread/internal/backend/cache"
 	"github.com/TypicalAM/goread/internal/ui/tab"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,11 +17,27 @@ type ArticleItem struct {
 	RawDesc         string
 	MarkdownContent string
 	FeedURL         string
+	ID              cache.ArticleID
+	Tags            []string
+	Note            string
 }
 
-// FilterValue fulfills the list.Item interface
+// tagSeparator comes before every tag in the filter value of an article, a tag can't contain it
+const tagSeparator = "\n#"
+
+// FilterValue fulfills the list.Item interface, the tags are a part of it so that the list can be filtered by them
 func (a ArticleItem) FilterValue() string {
-	return a.ArtTitle
+	value := strings.ReplaceAll(a.ArtTitle, "\n", " ")
+	for _, tag := range a.Tags {
+		value += tagSeparator + tag
+	}
+
+	return value
+}
+
+// FilterValueTags returns the tags of an article from its filter value
+func FilterValueTags(value string) []string {
+	return strings.Split(value, tagSeparator)[1:]
 }
 
 // Title fulfills the list.DefaultItem interface
@@ -45,12 +64,45 @@ type FetchArticleSuccessMsg struct {
 	Items []list.Item
 }
 
+// FeedsRefreshedMsg is sent when the background refresh finishes, it contains the amount of new articles per feed.
+type FeedsRefreshedMsg struct {
+	NewArticles map[string]int
+}
+
+// ArticlesReloadedMsg is sent when the articles of an already open feed are reloaded.
+type ArticlesReloadedMsg struct {
+	FeedName string
+	Items    []list.Item
+}
+
+// ArticleExtractedMsg is sent when the full content of an article is extracted from its page.
+type ArticleExtractedMsg struct {
+	URL             string
+	MarkdownContent string
+}
+
+// AutosaveFailedMsg is sent when the state couldn't be written to disk.
+type AutosaveFailedMsg struct{ Err error }
+
+// SavedExportedMsg is sent when the saved articles are exported.
+type SavedExportedMsg struct {
+	Path  string
+	Count int
+}
+
 // FetchErrorMsg is sent on fetch error.
 type FetchErrorMsg struct {
 	Err         error
 	Description string
 }
 
+// FeedsDiscoveredMsg is sent when feed autodiscovery finishes.
+type FeedsDiscoveredMsg struct {
+	Category   string
+	Name       string
+	Candidates []cache.Candidate
+}
+
 // NewItemMsg contains info the browser needs to know to add a new item.
 type NewItemMsg struct{ Sender tab.Tab }
 
@@ -59,6 +111,14 @@ func NewItem(sender tab.Tab) tea.Cmd {
 	return func() tea.Msg { return NewItemMsg{sender} }
 }
 
+// NewCategoryMsg contains info the browser needs to know to add a subcategory.
+type NewCategoryMsg struct{ Parent string }
+
+// NewCategory is called from a tab to tell the browser that a subcategory needs to be added.
+func NewCategory(parent string) tea.Cmd {
+	return func() tea.Msg { return NewCategoryMsg{parent} }
+}
+
 // EditItemMsg contains info the browser needs to know to edit an item.
 type EditItemMsg struct {
 	Sender    tab.Tab
@@ -70,6 +130,52 @@ func EditItem(sender tab.Tab, fields []string) tea.Cmd {
 	return func() tea.Msg { return EditItemMsg{sender, fields} }
 }
 
+// AnnotateItemMsg contains info the browser needs to know to edit the tags and the note of a saved article.
+type AnnotateItemMsg struct {
+	ID   cache.ArticleID
+	Tags []string
+	Note string
+}
+
+// AnnotateItem is called from a tab to tell the browser that the tags and the note of a saved article need to be edited.
+func AnnotateItem(id cache.ArticleID, tags []string, note string) tea.Cmd {
+	return func() tea.Msg { return AnnotateItemMsg{id, tags, note} }
+}
+
+// EditFiltersMsg contains info the browser needs to know to edit the filter rules of an item.
+type EditFiltersMsg struct {
+	Sender   tab.Tab
+	ItemName string
+}
+
+// EditFilters is called from a tab to tell the browser that the filter rules of an item need to be edited.
+func EditFilters(sender tab.Tab, itemName string) tea.Cmd {
+	return func() tea.Msg { return EditFiltersMsg{sender, itemName} }
+}
+
+// MoveItemMsg contains info the browser needs to know to move an item to another category.
+type MoveItemMsg struct {
+	Sender   tab.Tab
+	ItemName string
+}
+
+// MoveItem is called from a tab to tell the browser that an item needs to be moved to another category.
+func MoveItem(sender tab.Tab, itemName string) tea.Cmd {
+	return func() tea.Msg { return MoveItemMsg{sender, itemName} }
+}
+
+// ReorderItemMsg contains info the browser needs to know to change the position of an item.
+type ReorderItemMsg struct {
+	Sender   tab.Tab
+	ItemName string
+	Offset   int
+}
+
+// ReorderItem is called from a tab to tell the browser that an item needs to be moved up or down.
+func ReorderItem(sender tab.Tab, itemName string, offset int) tea.Cmd {
+	return func() tea.Msg { return ReorderItemMsg{sender, itemName, offset} }
+}
+
 // DeleteItemMsg contains info the browser needs to know to delete an item.
 type DeleteItemMsg struct {
 	Sender   tab.Tab
@@ -92,6 +198,33 @@ func DownloadItem(feedName string, index int) tea.Cmd {
 	return func() tea.Msg { return DownloadItemMsg{feedName, index} }
 }
 
+// ExtractNewItemsMsg is sent by a feed tab once its articles are shown, so that the full content of the new ones can be extracted.
+type ExtractNewItemsMsg string
+
+// ExtractNewItems is called from a tab to tell the browser that the full content of its new items can be extracted.
+func ExtractNewItems(feedName string) tea.Cmd {
+	return func() tea.Msg { return ExtractNewItemsMsg(feedName) }
+}
+
+// ExtractItemMsg contains info the browser needs to know to fetch the full content of an item.
+type ExtractItemMsg struct {
+	FeedName string
+	Index    int
+}
+
+// ExtractItem is called from a tab to tell the browser that the full content of an item needs to be fetched.
+func ExtractItem(feedName string, index int) tea.Cmd {
+	return func() tea.Msg { return ExtractItemMsg{feedName, index} }
+}
+
+// ExportSavedMsg tells the browser that the saved articles need to be exported.
+type ExportSavedMsg struct{}
+
+// ExportSaved is called from a tab to tell the browser that the saved articles need to be exported.
+func ExportSaved() tea.Cmd {
+	return func() tea.Msg { return ExportSavedMsg{} }
+}
+
 // MakeChoiceMsg contains info needed to create a binary choice prompt.
 type MakeChoiceMsg struct {
 	Question string
@@ -104,19 +237,19 @@ func MakeChoice(question string, defaultChoice bool) tea.Cmd {
 }
 
 // MarkAsReadMsg contains info needed to mark an item as read.
-type MarkAsReadMsg string
+type MarkAsReadMsg cache.ArticleID
 
 // MarkAsRead is called from a tab to tell the browser that an item needs to be marked as read.
-func MarkAsRead(url string) tea.Cmd {
-	return func() tea.Msg { return MarkAsReadMsg(url) }
+func MarkAsRead(id cache.ArticleID) tea.Cmd {
+	return func() tea.Msg { return MarkAsReadMsg(id) }
 }
 
 // MarkAsUnreadMsg contains info needed to mark an item as unread.
-type MarkAsUnreadMsg string
+type MarkAsUnreadMsg cache.ArticleID
 
 // MarkAsUnread is called from a tab to tell the browser that an item needs to be marked as unread.
-func MarkAsUnread(url string) tea.Cmd {
-	return func() tea.Msg { return MarkAsUnreadMsg(url) }
+func MarkAsUnread(id cache.ArticleID) tea.Cmd {
+	return func() tea.Msg { return MarkAsUnreadMsg(id) }
 }
 
 // SetEnableKeybindMsg contains the desired state of the keybinds.
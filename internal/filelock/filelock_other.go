@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package filelock
+
+import "os"
+
+// lockFile does nothing on the platforms without file locking, every instance gets the lock
+func lockFile(_ *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on the platforms without file locking
+func unlockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package filelock
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// TestTryLock if we get an error then a locked directory can be locked again or the lock isn't released
+func TestTryLock(t *testing.T) {
+	if runtime.GOOS != "windows" && runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip()
+		return
+	}
+
+	dir := t.TempDir()
+	lock, err := TryLock(dir)
+	if err != nil {
+		t.Fatalf("couldn't lock the directory: %v", err)
+	}
+
+	if _, err = TryLock(dir); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+
+	if err = lock.Unlock(); err != nil {
+		t.Fatalf("couldn't unlock the directory: %v", err)
+	}
+
+	lock, err = TryLock(dir)
+	if err != nil {
+		t.Fatalf("couldn't lock the directory again: %v", err)
+	}
+
+	if err = lock.Unlock(); err != nil {
+		t.Fatalf("couldn't unlock the directory: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package migrate upgrades the files written by older versions of goread. Every file carries a
+// version field, files from before the versioning are version zero. The migrations run in memory
+// when a file is loaded, the original file is backed up first and is replaced the next time goread
+// saves it.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/TypicalAM/goread/internal/atomicfile"
+)
+
+// ErrTooNew is returned when a file was written by a newer version of goread
+var ErrTooNew = errors.New("the file was written by a newer version of goread")
+
+// ErrBackupFailed is returned when a file can't be backed up before it is upgraded
+var ErrBackupFailed = errors.New("the file couldn't be backed up before upgrading it")
+
+// Format is the encoding of a file
+type Format int
+
+const (
+	// JSON files keep the version in a top level "version" field
+	JSON Format = iota
+
+	// YAML files keep the version in a top level "version" key
+	YAML
+)
+
+// Migration upgrades the contents of a file from one version to the next one
+type Migration func(data []byte) ([]byte, error)
+
+// Schema describes the versions of a file format
+type Schema struct {
+	// Name is used in the messages about the file
+	Name string
+
+	// Format is the encoding of the file
+	Format Format
+
+	// Version is the current version of the format
+	Version int
+
+	// Migrations are keyed by the version they upgrade from, the versions which only
+	// added the version field don't need a migration
+	Migrations map[int]Migration
+}
+
+// versionHeader is the part of every file which holds its version
+type versionHeader struct {
+	Version int `json:"version" yaml:"version"`
+}
+
+// Detect returns the version of the file, files without a version are version zero
+func (s Schema) Detect(data []byte) (int, error) {
+	var header versionHeader
+	var err error
+	switch s.Format {
+	case JSON:
+		err = json.Unmarshal(data, &header)
+
+	case YAML:
+		err = yaml.Unmarshal(data, &header)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("migrate.Detect: %w", err)
+	}
+
+	if header.Version < 0 {
+		return 0, fmt.Errorf("migrate.Detect: invalid %s version %d", s.Name, header.Version)
+	}
+
+	return header.Version, nil
+}
+
+// Upgrade migrates the contents of the file at the path to the current version. Before anything is
+// migrated, the original file is copied next to it with the old version in its name. ErrTooNew is
+// returned for the files written by a newer goread and ErrBackupFailed when the copy can't be made,
+// since loading them could lose data.
+func (s Schema) Upgrade(path string, data []byte) ([]byte, error) {
+	version, err := s.Detect(data)
+	if err != nil {
+		return nil, fmt.Errorf("migrate.Upgrade: %w", err)
+	}
+
+	if version > s.Version {
+		return nil, fmt.Errorf(
+			"migrate.Upgrade: %w: %s is version %d, this goread supports up to version %d",
+			ErrTooNew, path, version, s.Version,
+		)
+	}
+
+	if version == s.Version {
+		return data, nil
+	}
+
+	log.Printf("Upgrading the %s from version %d to %d\n", s.Name, version, s.Version)
+	if err = backup(path, version, data); err != nil {
+		return nil, fmt.Errorf("migrate.Upgrade: %w: %s: %v", ErrBackupFailed, path, err)
+	}
+
+	for ; version < s.Version; version++ {
+		migration, ok := s.Migrations[version]
+		if !ok {
+			continue
+		}
+
+		if data, err = migration(data); err != nil {
+			return nil, fmt.Errorf("migrate.Upgrade: %s version %d: %w", s.Name, version, err)
+		}
+	}
+
+	return data, nil
+}
+
+// BackupPath returns the path of the backup of a file at the given version
+func BackupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// backup copies the original file before it is upgraded, an existing backup is kept
+// since it is the oldest copy of the file
+func backup(path string, version int, data []byte) error {
+	backupPath := BackupPath(path, version)
+	if _, err := os.Stat(backupPath); err == nil {
+		return nil
+	}
+
+	log.Println("Backing up", path, "to", backupPath)
+	return atomicfile.WriteFile(backupPath, data, 0600)
+}
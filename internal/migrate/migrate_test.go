@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSchema renames the "colour" key to "color" in version 1 and only adds the version in version 0
+var testSchema = Schema{
+	Name:    "test file",
+	Format:  YAML,
+	Version: 2,
+	Migrations: map[int]Migration{
+		1: func(data []byte) ([]byte, error) {
+			return []byte(strings.ReplaceAll(string(data), "colour:", "color:")), nil
+		},
+	},
+}
+
+// TestDetect if we get an error then the versions of the files are not detected
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		format   Format
+		data     string
+		expected int
+	}{
+		{JSON, `{"version": 3, "content": {}}`, 3},
+		{JSON, `{"content": {}}`, 0},
+		{YAML, "version: 2\ncategories: []\n", 2},
+		{YAML, "categories: []\n", 0},
+		{YAML, "", 0},
+	}
+
+	for _, test := range tests {
+		version, err := Schema{Format: test.format}.Detect([]byte(test.data))
+		if err != nil || version != test.expected {
+			t.Errorf("expected %q to be version %d, got %d (%v)", test.data, test.expected, version, err)
+		}
+	}
+
+	if _, err := (Schema{Format: JSON}).Detect([]byte("{")); err == nil {
+		t.Error("expected an error for a corrupted file")
+	}
+}
+
+// TestUpgrade if we get an error then the migrations don't run in order or the original isn't backed up
+func TestUpgrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.yml")
+	original := []byte("version: 1\ncolour: red\n")
+	if err := os.WriteFile(path, original, 0600); err != nil {
+		t.Fatalf("couldn't write the file: %v", err)
+	}
+
+	data, err := testSchema.Upgrade(path, original)
+	if err != nil {
+		t.Fatalf("couldn't upgrade the file: %v", err)
+	}
+
+	if string(data) != "version: 1\ncolor: red\n" {
+		t.Errorf("expected the migration to run, got %q", data)
+	}
+
+	backup, err := os.ReadFile(BackupPath(path, 1))
+	if err != nil {
+		t.Fatalf("expected a backup of the original: %v", err)
+	}
+
+	if string(backup) != string(original) {
+		t.Errorf("expected the backup to hold the original, got %q", backup)
+	}
+
+	// The migrations of older versions are skipped
+	current := []byte("version: 2\ncolour: red\n")
+	if data, err = testSchema.Upgrade(path, current); err != nil || string(data) != string(current) {
+		t.Errorf("expected the current file to stay as it is, got %q (%v)", data, err)
+	}
+
+	if _, err = os.Stat(BackupPath(path, 2)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup of the current file, got %v", err)
+	}
+}
+
+// TestUpgradeBackupFailed if we get an error then a file is upgraded without a backup of the original
+func TestUpgradeBackupFailed(t *testing.T) {
+	// NOTE: The file is in a "directory" which is a regular file, so the backup can't be written next to it
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(parent, nil, 0600); err != nil {
+		t.Fatalf("couldn't write the file: %v", err)
+	}
+
+	path := filepath.Join(parent, "test.yml")
+	data, err := testSchema.Upgrade(path, []byte("version: 1\ncolour: red\n"))
+	if !errors.Is(err, ErrBackupFailed) || data != nil {
+		t.Errorf("expected ErrBackupFailed and no data, got %q (%v)", data, err)
+	}
+}
+
+// TestUpgradeTooNew if we get an error then the files from a newer version are loaded
+func TestUpgradeTooNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.yml")
+	_, err := testSchema.Upgrade(path, []byte("version: 3\n"))
+	if !errors.Is(err, ErrTooNew) {
+		t.Fatalf("expected ErrTooNew, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "version 3") || !strings.Contains(err.Error(), "up to version 2") {
+		t.Errorf("expected the error to name the versions, got %q", err)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/glamour/ansi"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/TypicalAM/goread/internal/migrate"
 )
 
 // Default is the default colorscheme
@@ -29,9 +31,16 @@ var Default = Colors{
 	MarkdownStyle: glamour.DraculaStyleConfig,
 }
 
+// colorsVersion is the version of the colorscheme file format
+const colorsVersion = 1
+
+// colorsSchema describes the versions of the colorscheme file
+var colorsSchema = migrate.Schema{Name: "colorscheme", Format: migrate.JSON, Version: colorsVersion}
+
 // Colors is a struct that contains all the colors for the application
 type Colors struct {
 	MarkdownStyle ansi.StyleConfig `json:"-"` // Just generate this at runtime
+	Version       int              `json:"version"`
 	Color2        lipgloss.Color   `json:"color2"`
 	BgDarker      lipgloss.Color   `json:"bg_darker"`
 	Text          lipgloss.Color   `json:"text"`
@@ -71,6 +80,10 @@ func (c *Colors) Load() error {
 		return fmt.Errorf("theme.Load: %w", err)
 	}
 
+	if fileContent, err = colorsSchema.Upgrade(c.FilePath, fileContent); err != nil {
+		return fmt.Errorf("theme.Load: %w", err)
+	}
+
 	if err = json.Unmarshal(fileContent, c); err != nil {
 		return fmt.Errorf("theme.Load: %w", err)
 	}
@@ -81,6 +94,7 @@ func (c *Colors) Load() error {
 
 // Save saves the colorscheme to a JSON file
 func (c Colors) Save() error {
+	c.Version = colorsVersion
 	jsonData, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return fmt.Errorf("theme.Save: %w", err)
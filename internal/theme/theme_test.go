@@ -1,7 +1,12 @@
 package theme
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/TypicalAM/goread/internal/migrate"
 )
 
 // TestThemeLoadNoFile if we get an error then the default theme is not generated
@@ -23,6 +28,23 @@ func TestThemeLoadFile(t *testing.T) {
 	}
 }
 
+// TestThemeLoadTooNew if we get an error then a colorscheme from a newer version is loaded
+func TestThemeLoadTooNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "colorscheme.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "text": "#000000"}`), 0600); err != nil {
+		t.Fatal("Couldn't write the colorscheme", err)
+	}
+
+	colors, err := New(path)
+	if err != nil {
+		t.Error("Theme couldn't be created", err)
+	}
+
+	if err = colors.Load(); !errors.Is(err, migrate.ErrTooNew) {
+		t.Error("Expected the newer colorscheme to be refused, got", err)
+	}
+}
+
 // TestPywalConvert if we get an error then the conversion doesn't work correctly
 func TestPywalConvert(t *testing.T) {
 	colors := Default
@@ -6,8 +6,10 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/TypicalAM/goread/internal/backend"
+	"github.com/TypicalAM/goread/internal/backend/cache"
 	"github.com/TypicalAM/goread/internal/backend/rss"
 	"github.com/TypicalAM/goread/internal/theme"
 	"github.com/TypicalAM/goread/internal/ui/popup"
@@ -22,6 +24,18 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// DefaultAutoRefresh is the default interval between background refreshes of the expired feeds, zero disables them
+var DefaultAutoRefresh = 10 * time.Minute
+
+// DefaultAutosave is the default interval between saving the changed state to disk, zero disables it
+var DefaultAutosave = time.Minute
+
+// autoRefreshMsg is sent when it's time to refresh the expired feeds in the background
+type autoRefreshMsg struct{}
+
+// autosaveMsg is sent when it's time to save the changed state to disk
+type autosaveMsg struct{}
+
 // Model is used to store the state of the application
 type Model struct {
 	popup          popup.Window
@@ -32,6 +46,7 @@ type Model struct {
 	keymap         Keymap
 	tabs           []tab.Tab
 	activeTab      int
+	newArticles    int
 	height         int
 	width          int
 	waitingForSize bool
@@ -43,12 +58,24 @@ type Model struct {
 func New(colors *theme.Colors, backend *backend.Backend) Model {
 	log.Println("Initializing the browser")
 
+	msg := "Pro-tip - press [ctrl+h] to view the help page"
+	if duplicates := backend.Rss.Duplicates(); len(duplicates) > 0 {
+		msg = fmt.Sprintf("%s is subscribed to %d times, look for the ⚠ marks in the categories", duplicates[0][0], len(duplicates[0]))
+		if len(duplicates) > 1 {
+			msg = fmt.Sprintf("%d feeds are subscribed to more than once, look for the ⚠ marks in the categories", len(duplicates))
+		}
+	}
+
+	if backend.ReadOnly {
+		msg = "Another goread instance is running - changes made here won't be saved"
+	}
+
 	return Model{
 		style:          newStyle(colors),
 		backend:        backend,
 		waitingForSize: true,
 		keymap:         DefaultKeymap,
-		msg:            "Pro-tip - press [ctrl+h] to view the help page",
+		msg:            msg,
 	}
 }
 
@@ -70,6 +97,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.quitting = true
 		return m, tea.Quit
 
+	case autoRefreshMsg:
+		if m.offline {
+			return m, m.scheduleRefresh()
+		}
+
+		log.Println("Refreshing the expired feeds in the background")
+		return m, m.backend.RefreshExpired()
+
+	case autosaveMsg:
+		return m, tea.Batch(m.backend.Autosave(false), m.scheduleAutosave())
+
+	case backend.AutosaveFailedMsg:
+		log.Println("Autosave failed:", msg.Err)
+		m.msg = fmt.Sprintf("Couldn't save the changes: %s", unwrapErrs(msg.Err))
+		return m, nil
+
+	case backend.FeedsRefreshedMsg:
+		return m.reloadTabs(msg)
+
+	case backend.ArticlesReloadedMsg:
+		cmds := make([]tea.Cmd, len(m.tabs))
+		for i := range m.tabs {
+			updated, cmd := m.tabs[i].Update(msg)
+			m.tabs[i], cmds[i] = updated.(tab.Tab), cmd
+		}
+
+		return m, tea.Batch(cmds...)
+
+	case backend.ExtractNewItemsMsg:
+		return m, m.backend.ExtractNewArticles(string(msg))
+
+	case backend.ExtractItemMsg:
+		m.msg = "Fetching the full article"
+		return m, m.backend.ExtractArticle(msg.FeedName, msg.Index)
+
+	case backend.ArticleExtractedMsg:
+		m.msg = "Fetched the full article"
+		cmds := make([]tea.Cmd, len(m.tabs))
+		for i := range m.tabs {
+			updated, cmd := m.tabs[i].Update(msg)
+			m.tabs[i], cmds[i] = updated.(tab.Tab), cmd
+		}
+
+		return m, tea.Batch(cmds...)
+
 	case backend.FetchErrorMsg:
 		// Update the underlying tab in case it also handles error input
 		log.Printf("Error fetching data in tab %d: %v \n", m.activeTab, msg.Err)
@@ -90,7 +162,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m.msg = fmt.Sprintf("Updated category %s", msg.Name)
-			return m, m.backend.FetchCategories("")
+			return m, tea.Batch(m.backend.FetchCategories(""), m.backend.Autosave(true))
 		}
 
 		if err := m.backend.Rss.AddCategory(msg.Name, msg.Desc); err != nil {
@@ -100,7 +172,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.msg = fmt.Sprintf("Added category %s", msg.Name)
-		return m, m.backend.FetchCategories("")
+		return m, tea.Batch(m.backend.FetchCategories(""), m.backend.Autosave(true))
 
 	case category.ChosenFeedMsg:
 		m.popup = nil
@@ -114,17 +186,48 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m.msg = fmt.Sprintf("Updated feed %s", msg.Name)
-			return m, m.backend.FetchFeeds(msg.Parent)
+			return m, tea.Batch(m.backend.FetchFeeds(msg.Parent), m.backend.Autosave(true))
 		}
 
-		if err := m.backend.Rss.AddFeed(msg.Parent, msg.Name, msg.URL); err != nil {
-			errMsg := fmt.Sprintf("Error adding feed: %s", unwrapErrs(err))
+		m.msg = fmt.Sprintf("Looking for feeds at %s", msg.URL)
+		return m, m.backend.DiscoverFeeds(msg.Parent, msg.Name, msg.URL)
+
+	case category.ChosenCategoryMsg:
+		m.popup = nil
+		m.keymap.SetEnabled(true)
+
+		if msg.IsEdit {
+			if err := m.backend.Rss.UpdateCategory(rss.JoinPath(msg.Parent, msg.OldName), msg.Name, msg.Desc); err != nil {
+				errMsg := fmt.Sprintf("Error updating subcategory: %s", unwrapErrs(err))
+				m, cmd := m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+				return m, tea.Batch(cmd, m.backend.FetchFeeds(msg.Parent))
+			}
+
+			m.msg = fmt.Sprintf("Updated subcategory %s", msg.Name)
+			return m, tea.Batch(m.backend.FetchFeeds(msg.Parent), m.backend.Autosave(true))
+		}
+
+		if err := m.backend.Rss.AddSubcategory(msg.Parent, msg.Name, msg.Desc); err != nil {
+			errMsg := fmt.Sprintf("Error adding subcategory: %s", unwrapErrs(err))
 			m, cmd := m.showPopup(lollypops.NewError(m.style.colors, errMsg))
 			return m, tea.Batch(cmd, m.backend.FetchFeeds(msg.Parent))
 		}
 
-		m.msg = fmt.Sprintf("Added feed %s", msg.Name)
-		return m, m.backend.FetchFeeds(msg.Parent)
+		m.msg = fmt.Sprintf("Added subcategory %s", msg.Name)
+		return m, tea.Batch(m.backend.FetchFeeds(msg.Parent), m.backend.Autosave(true))
+
+	case backend.FeedsDiscoveredMsg:
+		if len(msg.Candidates) == 1 {
+			return m.addFeed(msg.Category, msg.Candidates[0].Name(msg.Name), msg.Candidates[0].URL)
+		}
+
+		m.keymap.SetEnabled(false)
+		return m.showPopup(category.NewDiscoveryPopup(m.style.colors, msg.Category, msg.Name, msg.Candidates))
+
+	case category.ChosenCandidateMsg:
+		m.popup = nil
+		m.keymap.SetEnabled(true)
+		return m.addFeed(msg.Parent, msg.Name, msg.URL)
 
 	case tab.NewTabMsg:
 		return m.createNewTab(msg)
@@ -142,6 +245,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 
+	case backend.NewCategoryMsg:
+		m.keymap.SetEnabled(false)
+		return m.showPopup(category.NewCategoryPopup(m.style.colors, "", "", msg.Parent))
+
 	case backend.EditItemMsg:
 		oldName, oldDesc := msg.OldFields[0], msg.OldFields[1]
 		m.keymap.SetEnabled(false)
@@ -150,24 +257,86 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case overview.Model:
 			return m.showPopup(overview.NewPopup(m.style.colors, oldName, oldDesc))
 		case category.Model:
+			if rss.IsCategoryPath(oldName) {
+				name := strings.TrimSuffix(oldName, rss.PathSeparator)
+				return m.showPopup(category.NewCategoryPopup(m.style.colors, name, oldDesc, msg.Sender.Title()))
+			}
+
 			return m.showPopup(category.NewPopup(m.style.colors, oldName, oldDesc, msg.Sender.Title()))
-		case feed.Model:
 		}
 
 		return m, nil
 
+	case backend.AnnotateItemMsg:
+		m.keymap.SetEnabled(false)
+		return m.showPopup(feed.NewPopup(m.style.colors, msg.ID, strings.Join(msg.Tags, ", "), msg.Note))
+
+	case backend.EditFiltersMsg:
+		return m.editFilters(msg)
+
+	case chosenFiltersMsg:
+		m.popup = nil
+		m.keymap.SetEnabled(true)
+
+		if err := m.backend.Rss.UpdateFilters(msg.category, msg.feed, msg.rules); err != nil {
+			errMsg := fmt.Sprintf("Error updating the filters: %s", unwrapErrs(err))
+			return m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+		}
+
+		m.msg = fmt.Sprintf("Updated the filters of %s", msg.name)
+		return m, m.backend.Autosave(true)
+
+	case backend.MoveItemMsg:
+		return m.moveItem(msg)
+
+	case category.ChosenDestinationMsg:
+		m.popup = nil
+		m.keymap.SetEnabled(true)
+
+		if err := m.backend.Rss.MoveFeed(msg.Parent, msg.Name, msg.Target); err != nil {
+			errMsg := fmt.Sprintf("Error moving feed %s: %s", msg.Name, unwrapErrs(err))
+			m, cmd := m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+			return m, tea.Batch(cmd, m.backend.FetchFeeds(msg.Parent))
+		}
+
+		m.msg = fmt.Sprintf("Moved feed %s to %s", msg.Name, strings.TrimSuffix(msg.Target, rss.PathSeparator))
+		return m, tea.Batch(m.backend.FetchFeeds(msg.Parent), m.backend.Autosave(true))
+
+	case backend.ReorderItemMsg:
+		return m.reorderItem(msg)
+
+	case feed.ChosenAnnotationMsg:
+		m.popup = nil
+		m.keymap.SetEnabled(true)
+
+		if err := m.backend.Cache.Annotate(msg.ID, cache.ParseTags(msg.Tags), msg.Note); err != nil {
+			errMsg := fmt.Sprintf("Error editing the saved article: %s", unwrapErrs(err))
+			return m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+		}
+
+		m.msg = "Updated the tags and the note"
+		return m, tea.Batch(m.backend.FetchDownloadedArticles("", false), m.backend.Autosave(false))
+
 	case backend.DeleteItemMsg:
 		return m.deleteItem(msg)
 
 	case backend.DownloadItemMsg:
 		return m.downloadItem(msg)
 
+	case backend.ExportSavedMsg:
+		m.msg = "Exporting the saved articles"
+		return m, m.backend.ExportDownloaded()
+
+	case backend.SavedExportedMsg:
+		m.msg = fmt.Sprintf("Exported %d saved articles to %s", msg.Count, msg.Path)
+		return m, nil
+
 	case backend.MarkAsReadMsg:
-		m.backend.ReadStatus.MarkAsRead(string(msg))
+		m.backend.ReadStatus.MarkAsRead(cache.ArticleID(msg))
 		return m, nil
 
 	case backend.MarkAsUnreadMsg:
-		m.backend.ReadStatus.MarkAsUnread(string(msg))
+		m.backend.ReadStatus.MarkAsUnread(cache.ArticleID(msg))
 		return m, nil
 
 	case backend.MakeChoiceMsg:
@@ -201,6 +370,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		log.Println("Disabling keybinds, propagating")
 
 	case tea.KeyMsg:
+		// The user is looking at the screen, so the new articles are no longer new
+		m.newArticles = 0
+
 		switch {
 		case msg.String() == "ctrl+c":
 			m.quitting = true
@@ -332,7 +504,55 @@ func (m Model) waitForSize(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.backend.FetchCategories,
 	))
 
-	return m, m.tabs[0].Init()
+	return m, tea.Batch(m.tabs[0].Init(), m.scheduleRefresh(), m.scheduleAutosave())
+}
+
+// scheduleRefresh schedules the next background refresh
+func (m Model) scheduleRefresh() tea.Cmd {
+	if DefaultAutoRefresh <= 0 {
+		return nil
+	}
+
+	return tea.Tick(DefaultAutoRefresh, func(time.Time) tea.Msg { return autoRefreshMsg{} })
+}
+
+// scheduleAutosave schedules the next autosave
+func (m Model) scheduleAutosave() tea.Cmd {
+	if DefaultAutosave <= 0 {
+		return nil
+	}
+
+	return tea.Tick(DefaultAutosave, func(time.Time) tea.Msg { return autosaveMsg{} })
+}
+
+// reloadTabs updates the open feed tabs which got new articles during the background refresh
+func (m Model) reloadTabs(msg backend.FeedsRefreshedMsg) (tea.Model, tea.Cmd) {
+	total := 0
+	for _, count := range msg.NewArticles {
+		total += count
+	}
+
+	cmds := []tea.Cmd{m.scheduleRefresh()}
+	if total == 0 {
+		return m, tea.Batch(cmds...)
+	}
+
+	log.Println("Background refresh found", total, "new articles")
+	m.newArticles += total
+	for i := range m.tabs {
+		if _, ok := m.tabs[i].(feed.Model); !ok {
+			continue
+		}
+
+		title := m.tabs[i].Title()
+		if title == rss.AllFeedsName {
+			cmds = append(cmds, m.backend.ReloadArticles(title))
+		} else if msg.NewArticles[title] > 0 {
+			cmds = append(cmds, tea.Sequence(m.backend.ReloadArticles(title), m.backend.ExtractNewArticles(title)))
+		}
+	}
+
+	return m, tea.Batch(cmds...)
 }
 
 // createNewTab bootstraps the new tab and adds it to the model
@@ -345,7 +565,7 @@ func (m Model) createNewTab(msg tab.NewTabMsg) (Model, tea.Cmd) {
 		switch msg.Title {
 		case rss.AllFeedsName:
 			newTab = feed.New(m.style.colors, m.width, height, msg.Title, m.backend.FetchAllArticles).
-				DisableDeleting()
+				DisableDeleting().DisableExporting().DisableAnnotating()
 
 		case rss.DownloadedFeedsName:
 			newTab = feed.New(m.style.colors, m.width, height, msg.Title, m.backend.FetchDownloadedArticles).
@@ -356,8 +576,13 @@ func (m Model) createNewTab(msg tab.NewTabMsg) (Model, tea.Cmd) {
 		}
 
 	case category.Model:
+		if rss.IsCategoryPath(msg.Title) {
+			newTab = category.New(m.style.colors, m.width, height, msg.Title, m.backend.FetchFeeds)
+			break
+		}
+
 		newTab = feed.New(m.style.colors, m.width, height, msg.Title, m.backend.FetchArticles).
-			DisableDeleting()
+			DisableDeleting().DisableExporting().DisableAnnotating()
 	}
 
 	// Insert the tab after the active tab
@@ -368,6 +593,117 @@ func (m Model) createNewTab(msg tab.NewTabMsg) (Model, tea.Cmd) {
 	return m, newTab.Init()
 }
 
+// addFeed adds a feed to a category and refreshes the category
+func (m Model) addFeed(parent, name, url string) (tea.Model, tea.Cmd) {
+	if err := m.backend.Rss.AddFeed(parent, name, url); err != nil {
+		errMsg := fmt.Sprintf("Error adding feed: %s", unwrapErrs(err))
+		m, cmd := m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+		return m, tea.Batch(cmd, m.backend.FetchFeeds(parent))
+	}
+
+	m.msg = fmt.Sprintf("Added feed %s", name)
+	if locations := m.backend.Rss.FindURL(url); len(locations) > 1 {
+		m.msg = fmt.Sprintf("Added feed %s - it is already subscribed to as %s", name, locations[0])
+	}
+
+	return m, tea.Batch(m.backend.FetchFeeds(parent), m.backend.Autosave(true))
+}
+
+// moveItem asks where the focused feed of a category tab should be moved to
+func (m Model) moveItem(msg backend.MoveItemMsg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.Sender.(category.Model); !ok {
+		return m, nil
+	}
+
+	parent := msg.Sender.Title()
+	var targets []string
+	for _, path := range m.backend.Rss.CategoryPaths() {
+		name := strings.TrimSuffix(path, rss.PathSeparator)
+		if name == strings.TrimSuffix(parent, rss.PathSeparator) || name == rss.AllFeedsName || name == rss.DownloadedFeedsName {
+			continue
+		}
+
+		targets = append(targets, path)
+	}
+
+	if len(targets) == 0 {
+		return m.showPopup(lollypops.NewError(m.style.colors, "There is no other category to move the feed to"))
+	}
+
+	m.keymap.SetEnabled(false)
+	return m.showPopup(category.NewMovePopup(m.style.colors, parent, msg.ItemName, targets))
+}
+
+// reorderItem moves the focused item up or down, categories in the overview and feeds or subcategories
+// in a category tab
+func (m Model) reorderItem(msg backend.ReorderItemMsg) (tea.Model, tea.Cmd) {
+	var err error
+	var cmd tea.Cmd
+
+	switch msg.Sender.(type) {
+	case overview.Model:
+		cmd = m.backend.FetchCategories("")
+		err = m.backend.Rss.ReorderCategory(msg.ItemName, msg.Offset)
+
+	case category.Model:
+		parent := msg.Sender.Title()
+		cmd = m.backend.FetchFeeds(parent)
+		if rss.IsCategoryPath(msg.ItemName) {
+			err = m.backend.Rss.ReorderCategory(rss.JoinPath(parent, msg.ItemName), msg.Offset)
+		} else {
+			err = m.backend.Rss.ReorderFeed(parent, msg.ItemName, msg.Offset)
+		}
+
+	default:
+		return m, nil
+	}
+
+	if err != nil {
+		errMsg := fmt.Sprintf("Error moving %s: %s", msg.ItemName, unwrapErrs(err))
+		m, popupCmd := m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+		return m, tea.Batch(popupCmd, cmd)
+	}
+
+	return m, tea.Batch(cmd, m.backend.Autosave(true))
+}
+
+// editFilters shows the filter rules of the focused item. In the overview the "All Feeds" item stands for the
+// global rules, every other item is a category. In a category tab the item is a feed or a subcategory.
+func (m Model) editFilters(msg backend.EditFiltersMsg) (tea.Model, tea.Cmd) {
+	var catPath, feedName, name string
+	switch msg.Sender.(type) {
+	case overview.Model:
+		switch msg.ItemName {
+		case rss.AllFeedsName:
+			name = "all feeds"
+
+		case rss.DownloadedFeedsName:
+			return m.showPopup(lollypops.NewError(m.style.colors, "The saved articles aren't filtered"))
+
+		default:
+			catPath, name = msg.ItemName, msg.ItemName
+		}
+
+	case category.Model:
+		catPath, feedName, name = msg.Sender.Title(), msg.ItemName, msg.ItemName
+		if rss.IsCategoryPath(msg.ItemName) {
+			catPath, feedName = rss.JoinPath(catPath, msg.ItemName), ""
+		}
+
+	default:
+		return m, nil
+	}
+
+	rules, err := m.backend.Rss.GetFilters(catPath, feedName)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error getting the filters of %s: %s", name, unwrapErrs(err))
+		return m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+	}
+
+	m.keymap.SetEnabled(false)
+	return m.showPopup(newFilters(m.style.colors, catPath, feedName, name, rules))
+}
+
 // deleteItem deletes the focused item from the backend
 func (m Model) deleteItem(msg backend.DeleteItemMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
@@ -383,8 +719,18 @@ func (m Model) deleteItem(msg backend.DeleteItemMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case category.Model:
-		cmd = m.backend.FetchFeeds(m.tabs[m.activeTab].Title())
-		if err := m.backend.Rss.RemoveFeed(m.tabs[m.activeTab].Title(), msg.ItemName); err != nil {
+		parent := m.tabs[m.activeTab].Title()
+		cmd = m.backend.FetchFeeds(parent)
+		if rss.IsCategoryPath(msg.ItemName) {
+			if err := m.backend.Rss.RemoveCategory(rss.JoinPath(parent, msg.ItemName)); err != nil {
+				errMsg := fmt.Sprintf("Error deleting subcategory %s: %s", msg.ItemName, unwrapErrs(err))
+				return m.showPopup(lollypops.NewError(m.style.colors, errMsg))
+			}
+
+			break
+		}
+
+		if err := m.backend.Rss.RemoveFeed(parent, msg.ItemName); err != nil {
 			errMsg := fmt.Sprintf("Error deleting feed %s: %s", msg.ItemName, unwrapErrs(err))
 			return m.showPopup(lollypops.NewError(m.style.colors, errMsg))
 		}
@@ -406,7 +752,7 @@ func (m Model) deleteItem(msg backend.DeleteItemMsg) (tea.Model, tea.Cmd) {
 	}
 
 	log.Println(m.msg)
-	return m, cmd
+	return m, tea.Batch(cmd, m.backend.Autosave(true))
 }
 
 // downloadItem downloads an item
@@ -468,6 +814,13 @@ func (m Model) renderTabBar() string {
 // renderStatusBar is used to render the status bar at the bottom of the screen
 func (m Model) renderStatusBar() string {
 	row := m.style.styleStatusBarCell(m.tabs[m.activeTab], m.offline)
+	if m.newArticles > 0 {
+		row = lipgloss.JoinHorizontal(
+			lipgloss.Bottom,
+			row,
+			m.style.newArticlesCell.Render(fmt.Sprintf("%d new since you last looked", m.newArticles)),
+		)
+	}
 
 	var gapAmount int
 	if m.width-lipgloss.Width(row) < 0 {
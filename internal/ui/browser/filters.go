@@ -0,0 +1,119 @@
+package browser
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/TypicalAM/goread/internal/backend/filter"
+	"github.com/TypicalAM/goread/internal/theme"
+	"github.com/TypicalAM/goread/internal/ui/popup"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// chosenFiltersMsg is the message sent when the filter rules are saved
+type chosenFiltersMsg struct {
+	category string
+	feed     string
+	name     string
+	rules    []string
+}
+
+// Filters is a popup where the user can edit the filter rules of a feed, a category or the global ones.
+type Filters struct {
+	border   popup.TitleBorder
+	box      lipgloss.Style
+	hint     lipgloss.Style
+	errStyle lipgloss.Style
+	input    textarea.Model
+	err      string
+	category string
+	feed     string
+	name     string
+	width    int
+	height   int
+}
+
+// newFilters returns a new Filters popup for the rules of the given scope, the name describes the scope.
+func newFilters(colors *theme.Colors, category, feed, name string, rules []string) Filters {
+	width := 76
+	height := 16
+
+	input := textarea.New()
+	input.Placeholder = "hide title:sponsored or author:/^ads/"
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+	input.SetWidth(width - 12)
+	input.SetHeight(height - 9)
+	input.SetValue(strings.Join(rules, "\n"))
+	input.Focus()
+
+	return Filters{
+		border:   popup.NewTitleBorder("Filters - "+name, width, height, colors.Color1, lipgloss.NormalBorder()),
+		box:      lipgloss.NewStyle().Margin(1, 4),
+		hint:     lipgloss.NewStyle().Foreground(colors.TextDark).Italic(true),
+		errStyle: lipgloss.NewStyle().Foreground(colors.Color4),
+		input:    input,
+		category: category,
+		feed:     feed,
+		name:     name,
+		width:    width,
+		height:   height,
+	}
+}
+
+// GetSize returns the size of the popup.
+func (f Filters) GetSize() (width int, height int) {
+	return f.width, f.height
+}
+
+// Init initializes the popup.
+func (f Filters) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+// Update updates the popup, the rules are checked before they are saved.
+func (f Filters) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "ctrl+s" {
+		rules := strings.Split(f.input.Value(), "\n")
+		for _, rule := range rules {
+			if strings.TrimSpace(rule) == "" {
+				continue
+			}
+
+			if _, err := filter.Parse(rule); err != nil {
+				f.err = unwrapErrs(err).Error()
+				var ruleErr *filter.RuleError
+				if errors.As(err, &ruleErr) {
+					f.err = ruleErr.Reason + " in " + strings.TrimSpace(rule)
+				}
+
+				return f, nil
+			}
+		}
+
+		return f, f.confirm(rules)
+	}
+
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return f, cmd
+}
+
+// View renders the popup.
+func (f Filters) View() string {
+	hint := f.hint.Render("One rule per line, ctrl+s saves them and esc cancels")
+	if f.err != "" {
+		hint = f.errStyle.Render(f.err)
+	}
+
+	help := f.hint.Render("hide|show [title|author|tag|link|content:]word, \"text\" or /regex/")
+	ui := lipgloss.JoinVertical(lipgloss.Left, f.input.View(), "", help, hint)
+	return f.border.Render(f.box.Render(ui))
+}
+
+// confirm returns a tea.Cmd that tells the parent model about the new rules.
+func (f Filters) confirm(rules []string) tea.Cmd {
+	return func() tea.Msg { return chosenFiltersMsg{f.category, f.feed, f.name, rules} }
+}
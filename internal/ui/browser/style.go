@@ -18,6 +18,7 @@ type style struct {
 	statusBarGap         lipgloss.Style
 	statusBarCell        lipgloss.Style
 	offlineStatusBarCell lipgloss.Style
+	newArticlesCell      lipgloss.Style
 }
 
 // newStyle creates a new style
@@ -69,6 +70,7 @@ func newStyle(colors *theme.Colors) style {
 		statusBarGap:         statusBarGap,
 		statusBarCell:        statusBarCell,
 		offlineStatusBarCell: statusBarCell.Copy().Background(colors.TextDark),
+		newArticlesCell:      statusBarCell.Copy().Bold(false).Italic(true).Background(colors.Color5),
 	}
 }
 
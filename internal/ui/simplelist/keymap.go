@@ -9,6 +9,8 @@ type Keymap struct {
 	Down        key.Binding
 	PageUp      key.Binding
 	PageDown    key.Binding
+	Top         key.Binding
+	Bottom      key.Binding
 	QuickSelect key.Binding
 }
 
@@ -34,8 +36,16 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("shift+down", "J"),
 		key.WithHelp("shift+↓/J", "Page down"),
 	),
+	Top: key.NewBinding(
+		key.WithKeys("home", "g"),
+		key.WithHelp("home/g", "First item"),
+	),
+	Bottom: key.NewBinding(
+		key.WithKeys("end", "G"),
+		key.WithHelp("end/G", "Last item"),
+	),
 	QuickSelect: key.NewBinding(
 		key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
-		key.WithHelp("0-9", "Quick select"),
+		key.WithHelp("0-9", "Quick select (type the whole number)"),
 	),
 }
@@ -1,6 +1,7 @@
 package simplelist
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -13,8 +14,9 @@ import (
 
 // Item is an item in the list
 type Item struct {
-	title string
-	desc  string
+	title  string
+	desc   string
+	status string
 }
 
 // NewItem creates a new item
@@ -25,6 +27,17 @@ func NewItem(title, desc string) Item {
 	}
 }
 
+// WithStatus returns a copy of the item with a status shown next to its title
+func (i Item) WithStatus(status string) Item {
+	i.status = status
+	return i
+}
+
+// Status returns the status of the item
+func (i Item) Status() string {
+	return i.status
+}
+
 // Title returns the title of the item
 func (i Item) Title() string {
 	return i.title
@@ -46,6 +59,7 @@ type Model struct {
 	colors       *theme.Colors
 	style        listStyle
 	title        string
+	input        string
 	items        []list.Item
 	height       int
 	page         int
@@ -56,23 +70,16 @@ type Model struct {
 
 // New creates a new list
 func New(colors *theme.Colors, title string, height int, showDesc bool) Model {
-	style := newListStyle(colors)
-	var itemsPerPage int
-	if showDesc {
-		itemsPerPage = (height - lipgloss.Height(style.titleStyle.Render(""))) / 2
-	} else {
-		itemsPerPage = height - lipgloss.Height(style.titleStyle.Render(""))
+	m := Model{
+		Keymap:   DefaultKeymap,
+		colors:   colors,
+		title:    title,
+		showDesc: showDesc,
+		style:    newListStyle(colors),
 	}
 
-	return Model{
-		Keymap:       DefaultKeymap,
-		colors:       colors,
-		title:        title,
-		height:       height,
-		itemsPerPage: itemsPerPage,
-		showDesc:     showDesc,
-		style:        style,
-	}
+	m.SetHeight(height)
+	return m
 }
 
 // Init initializes the tab
@@ -82,39 +89,30 @@ func (m Model) Init() tea.Cmd {
 
 // Update updates the model
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
-	if msg, ok := msg.(tea.KeyMsg); ok {
-		switch {
-		case key.Matches(msg, m.Keymap.Up):
-			m.selected--
-			if m.selected < 0 {
-				m.selected = len(m.items) - 1
-				m.page = len(m.items) / m.itemsPerPage
-			}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(m.items) == 0 || key.Matches(keyMsg, m.Keymap.QuickSelect) {
+		return m, nil
+	}
 
-			// Check if the page needs to be changed
-			if m.selected < m.page*m.itemsPerPage {
-				m.page--
-			}
+	m.input = ""
+	switch {
+	case key.Matches(keyMsg, m.Keymap.Up):
+		m.SetIndex((m.selected - 1 + len(m.items)) % len(m.items))
 
-		case key.Matches(msg, m.Keymap.Down):
-			m.selected++
-			if m.selected >= len(m.items) {
-				m.selected = 0
-				m.page = 0
-			}
+	case key.Matches(keyMsg, m.Keymap.Down):
+		m.SetIndex((m.selected + 1) % len(m.items))
 
-			if m.selected >= (m.page+1)*m.itemsPerPage {
-				m.page++
-			}
+	case key.Matches(keyMsg, m.Keymap.PageUp):
+		m.SetIndex(m.selected - m.itemsPerPage)
 
-		case key.Matches(msg, m.Keymap.PageUp):
-			m.selected = 0
-			m.page = 0
+	case key.Matches(keyMsg, m.Keymap.PageDown):
+		m.SetIndex(m.selected + m.itemsPerPage)
 
-		case key.Matches(msg, m.Keymap.PageDown):
-			m.selected = len(m.items) - 1
-			m.page = len(m.items) / m.itemsPerPage
-		}
+	case key.Matches(keyMsg, m.Keymap.Top):
+		m.SetIndex(0)
+
+	case key.Matches(keyMsg, m.Keymap.Bottom):
+		m.SetIndex(len(m.items) - 1)
 	}
 
 	return m, nil
@@ -126,6 +124,10 @@ func (m Model) View() string {
 
 	b.WriteRune('\n')
 	b.WriteString(m.style.titleStyle.Render(m.title))
+	if m.input != "" {
+		b.WriteString(m.style.statusStyle.Render("go to " + m.input + "…"))
+	}
+
 	b.WriteRune('\n')
 
 	if len(m.items) == 0 {
@@ -134,12 +136,17 @@ func (m Model) View() string {
 		return b.String()
 	}
 
+	width := len(strconv.Itoa(len(m.items) - 1))
 	for i := m.itemsPerPage * m.page; i < m.itemsPerPage*(m.page+1); i++ {
 		if i >= len(m.items) {
 			break
 		}
 
-		b.WriteString(m.style.styleIndex(i, i == m.selected) + m.style.itemStyle.Render(m.items[i].FilterValue()))
+		b.WriteString(m.style.styleIndex(i, width, i == m.selected) + m.style.itemStyle.Render(m.items[i].FilterValue()))
+		if item, ok := m.items[i].(Item); ok && item.status != "" {
+			b.WriteString(m.style.statusStyle.Render(item.status))
+		}
+
 		b.WriteRune('\n')
 
 		if m.showDesc {
@@ -153,19 +160,33 @@ func (m Model) View() string {
 		}
 	}
 
+	if pages := m.pageCount(); pages > 1 {
+		b.WriteString(m.style.noItemsStyle.Render(fmt.Sprintf("page %d/%d", m.page+1, pages)))
+		b.WriteRune('\n')
+	}
+
 	b.WriteRune('\n')
 	return b.String()
 }
 
-// SetHeight sets the height of the list
+// SetHeight sets the height of the list, a line is left for the page indicator
 func (m *Model) SetHeight(height int) {
+	m.itemsPerPage = height - lipgloss.Height(m.style.titleStyle.Render("")) - 1
 	if m.showDesc {
-		m.itemsPerPage = (height - lipgloss.Height(m.style.titleStyle.Render(""))) / 2
-	} else {
-		m.itemsPerPage = height - lipgloss.Height(m.style.titleStyle.Render(""))
+		m.itemsPerPage /= 2
+	}
+
+	if m.itemsPerPage < 1 {
+		m.itemsPerPage = 1
 	}
 
 	m.height = height
+	m.page = m.selected / m.itemsPerPage
+}
+
+// pageCount returns the amount of pages in the list
+func (m Model) pageCount() int {
+	return (len(m.items) + m.itemsPerPage - 1) / m.itemsPerPage
 }
 
 // Items returns the items in the list
@@ -173,9 +194,10 @@ func (m Model) Items() []list.Item {
 	return m.items
 }
 
-// SetItems sets the items in the list
+// SetItems sets the items in the list, the selection is kept within the new items
 func (m *Model) SetItems(items []list.Item) {
 	m.items = items
+	m.SetIndex(m.selected)
 }
 
 // IsEmpty checks if the list is empty
@@ -188,18 +210,32 @@ func (m Model) SelectedItem() list.Item {
 	return m.items[m.selected]
 }
 
-// GetItem checks if the list has an item and returns it
-func (m Model) GetItem(text string) (list.Item, bool) {
-	index, err := strconv.Atoi(text)
-	if err != nil {
+// QuickSelect moves the selection to the item with the typed index. The digits can be typed one after
+// another, the item is returned as soon as no other index starts with the typed ones - otherwise the
+// list waits for another digit and Enter opens the selected item. Any other key, or a digit which makes the
+// index too large, drops the typed digits.
+func (m *Model) QuickSelect(msg tea.KeyMsg) (list.Item, bool) {
+	index, err := strconv.Atoi(msg.String())
+	if err != nil || !key.Matches(msg, m.Keymap.QuickSelect) {
+		m.input = ""
 		return nil, false
 	}
 
-	if index >= len(m.items) || index < 0 {
+	// NOTE: A digit which makes the index too large isn't the start of a new one, it is a typo
+	input := m.input + msg.String()
+	if index, _ = strconv.Atoi(input); index >= len(m.items) {
+		m.input = ""
 		return nil, false
 	}
 
-	return m.items[index], true
+	m.input = input
+	m.SetIndex(index)
+	if index == 0 || index*10 >= len(m.items) {
+		m.input = ""
+		return m.items[index], true
+	}
+
+	return nil, false
 }
 
 // Index returns the index of the selected item
@@ -207,9 +243,18 @@ func (m Model) Index() int {
 	return m.selected
 }
 
-// SetIndex sets the index of the selected item
+// SetIndex sets the index of the selected item and shows its page, the index is clamped to the items
 func (m *Model) SetIndex(index int) {
+	if index >= len(m.items) {
+		index = len(m.items) - 1
+	}
+
+	if index < 0 {
+		index = 0
+	}
+
 	m.selected = index
+	m.page = index / m.itemsPerPage
 }
 
 // ShortHelp returns the short help for the list
@@ -219,5 +264,5 @@ func (m Model) ShortHelp() []key.Binding {
 
 // FullHelp returns the full help for the list
 func (m Model) FullHelp() [][]key.Binding {
-	return [][]key.Binding{m.ShortHelp()}
+	return [][]key.Binding{m.ShortHelp(), {m.Keymap.PageUp, m.Keymap.PageDown, m.Keymap.Top, m.Keymap.Bottom}}
 }
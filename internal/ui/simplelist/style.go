@@ -14,6 +14,7 @@ type listStyle struct {
 	titleStyle   lipgloss.Style
 	noItemsStyle lipgloss.Style
 	itemStyle    lipgloss.Style
+	statusStyle  lipgloss.Style
 
 	bracketStyle lipgloss.Style
 	numberStyle  lipgloss.Style
@@ -35,6 +36,11 @@ func newListStyle(colors *theme.Colors) listStyle {
 		MarginLeft(3).
 		Foreground(colors.Color2)
 
+	statusStyle := lipgloss.NewStyle().
+		MarginLeft(2).
+		Foreground(colors.Color4).
+		Italic(true)
+
 	bracketStyle := lipgloss.NewStyle().
 		Foreground(colors.Color7)
 
@@ -46,6 +52,7 @@ func newListStyle(colors *theme.Colors) listStyle {
 		titleStyle:   titleStyle,
 		noItemsStyle: noItemsStyle,
 		itemStyle:    itemStyle,
+		statusStyle:  statusStyle,
 		bracketStyle: bracketStyle,
 		numberStyle:  numberStyle,
 	}
@@ -66,8 +73,8 @@ func (s listStyle) styleDescription(description string) string {
 	return arrowStyle.Render("⮡") + textStyle.Render(description)
 }
 
-// styleIndex will style the index of the item
-func (s listStyle) styleIndex(index int, isSelected bool) string {
+// styleIndex will style the index of the item, the indexes are padded to the given width
+func (s listStyle) styleIndex(index, width int, isSelected bool) string {
 	var b strings.Builder
 	b.WriteString("   ")
 	b.WriteString(s.bracketStyle.Render("["))
@@ -78,19 +85,8 @@ func (s listStyle) styleIndex(index int, isSelected bool) string {
 		style = style.Background(s.colors.Text)
 	}
 
-	// Check if the index is a digit
-	switch {
-	case index < 10:
-		b.WriteString(style.Render(fmt.Sprintf("%d", index)))
-
-	case index < 36:
-		b.WriteString(style.Render(fmt.Sprintf("%c", index+87)))
-
-	default:
-		b.WriteString(style.Render("-"))
-	}
-
 	// Render the whole index
+	b.WriteString(style.Render(fmt.Sprintf("%*d", width, index)))
 	b.WriteString(s.bracketStyle.Render("]"))
 	return b.String()
 }
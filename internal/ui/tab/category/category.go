@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"github.com/TypicalAM/goread/internal/backend"
+	"github.com/TypicalAM/goread/internal/backend/rss"
 	"github.com/TypicalAM/goread/internal/theme"
 	"github.com/TypicalAM/goread/internal/ui/popup/lollypops"
 	"github.com/TypicalAM/goread/internal/ui/simplelist"
@@ -107,13 +108,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if item, ok := m.list.QuickSelect(msg); ok {
+			return m, m.open(item.FilterValue())
+		}
+
 		switch {
 		case msg.String() == "esc":
 			return m, backend.StartQuitting()
 
 		case key.Matches(msg, m.list.Keymap.Open):
 			if !m.list.IsEmpty() {
-				return m, tab.NewTab(m, m.list.SelectedItem().FilterValue())
+				return m, m.open(m.list.SelectedItem().FilterValue())
 			}
 
 			return m, nil
@@ -121,6 +126,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keymap.NewFeed):
 			return m, backend.NewItem(m)
 
+		case key.Matches(msg, m.keymap.NewCategory):
+			return m, backend.NewCategory(m.title)
+
 		case key.Matches(msg, m.keymap.EditFeed):
 			// If the list is empty, return nothing
 			if m.list.IsEmpty() {
@@ -132,14 +140,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, backend.EditItem(m, fields)
 
 		case key.Matches(msg, m.keymap.DeleteFeed):
-			if !m.list.IsEmpty() {
-				return m, backend.MakeChoice("Delete this feed?", true)
+			if m.list.IsEmpty() {
+				return m, nil
+			}
+
+			if rss.IsCategoryPath(m.list.SelectedItem().FilterValue()) {
+				return m, backend.MakeChoice("Delete this subcategory?", true)
 			}
 
-		default:
-			if item, ok := m.list.GetItem(msg.String()); ok {
-				return m, tab.NewTab(m, item.FilterValue())
+			return m, backend.MakeChoice("Delete this feed?", true)
+
+		case key.Matches(msg, m.keymap.EditFilters):
+			if m.list.IsEmpty() {
+				return m, nil
+			}
+
+			return m, backend.EditFilters(m, m.list.SelectedItem().FilterValue())
+
+		case key.Matches(msg, m.keymap.MoveFeed):
+			if m.list.IsEmpty() || rss.IsCategoryPath(m.list.SelectedItem().FilterValue()) {
+				return m, nil
 			}
+
+			return m, backend.MoveItem(m, m.list.SelectedItem().FilterValue())
+
+		case key.Matches(msg, m.keymap.MoveUp):
+			return m.reorder(-1)
+
+		case key.Matches(msg, m.keymap.MoveDown):
+			return m.reorder(1)
 		}
 	}
 
@@ -148,6 +177,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// open opens a feed or a subcategory in a new tab, the subcategories are opened by their path
+func (m Model) open(name string) tea.Cmd {
+	if rss.IsCategoryPath(name) {
+		return tab.NewTab(m, rss.JoinPath(m.title, name))
+	}
+
+	return tab.NewTab(m, name)
+}
+
+// reorder moves the selected feed or subcategory by the offset. The subcategories are listed before
+// the feeds, so an item can only swap places with an item of the same kind.
+func (m Model) reorder(offset int) (tea.Model, tea.Cmd) {
+	if m.list.IsEmpty() {
+		return m, nil
+	}
+
+	name := m.list.SelectedItem().FilterValue()
+	target := m.list.Index() + offset
+	items := m.list.Items()
+	if target >= 0 && target < len(items) && rss.IsCategoryPath(items[target].FilterValue()) == rss.IsCategoryPath(name) {
+		m.list.SetIndex(target)
+	}
+
+	return m, backend.ReorderItem(m, name, offset)
+}
+
 // View returns the view of the tab
 func (m Model) View() string {
 	if !m.loaded {
@@ -159,7 +214,10 @@ func (m Model) View() string {
 
 // ShortHelp returns the short help for this tab
 func (m Model) ShortHelp() []key.Binding {
-	return []key.Binding{m.keymap.NewFeed, m.keymap.EditFeed, m.keymap.DeleteFeed}
+	return []key.Binding{
+		m.keymap.NewFeed, m.keymap.NewCategory, m.keymap.EditFeed, m.keymap.DeleteFeed,
+		m.keymap.EditFilters, m.keymap.MoveFeed, m.keymap.MoveUp, m.keymap.MoveDown,
+	}
 }
 
 // FullHelp returns the full help for this tab
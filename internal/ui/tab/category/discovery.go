@@ -0,0 +1,99 @@
+package category
+
+import (
+	"github.com/TypicalAM/goread/internal/backend/cache"
+	"github.com/TypicalAM/goread/internal/theme"
+	"github.com/TypicalAM/goread/internal/ui/popup"
+	"github.com/TypicalAM/goread/internal/ui/simplelist"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ChosenCandidateMsg is the message sent when one of the discovered feeds is chosen.
+type ChosenCandidateMsg struct {
+	Name   string
+	URL    string
+	Parent string
+}
+
+// DiscoveryPopup is the popup where a user chooses one of the feeds found on a website.
+type DiscoveryPopup struct {
+	list       simplelist.Model
+	border     popup.TitleBorder
+	box        lipgloss.Style
+	candidates []cache.Candidate
+	name       string
+	parent     string
+	width      int
+	height     int
+}
+
+// NewDiscoveryPopup returns a new discovery popup.
+func NewDiscoveryPopup(colors *theme.Colors, parent, name string, candidates []cache.Candidate) DiscoveryPopup {
+	width := 70
+	height := 2*len(candidates) + 6
+	if height > 22 {
+		height = 22
+	}
+
+	items := make([]list.Item, len(candidates))
+	for i, candidate := range candidates {
+		items[i] = simplelist.NewItem(candidate.Name(""), candidate.URL)
+	}
+
+	feeds := simplelist.New(colors, "Multiple feeds found", height-4, true)
+	feeds.SetItems(items)
+
+	return DiscoveryPopup{
+		list:       feeds,
+		border:     popup.NewTitleBorder("Choose a feed", width, height, colors.Color1, lipgloss.NormalBorder()),
+		box:        lipgloss.NewStyle().MaxWidth(width - 2),
+		candidates: candidates,
+		name:       name,
+		parent:     parent,
+		width:      width,
+		height:     height,
+	}
+}
+
+// Init initializes the popup.
+func (p DiscoveryPopup) Init() tea.Cmd {
+	return nil
+}
+
+// Update updates the popup.
+func (p DiscoveryPopup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if key.Matches(msg, p.list.Keymap.Open) {
+			return p, p.choose(p.list.Index())
+		}
+
+		if _, ok := p.list.QuickSelect(msg); ok {
+			return p, p.choose(p.list.Index())
+		}
+	}
+
+	var cmd tea.Cmd
+	p.list, cmd = p.list.Update(msg)
+	return p, cmd
+}
+
+// View renders the popup.
+func (p DiscoveryPopup) View() string {
+	return p.border.Render(p.box.Render(p.list.View()))
+}
+
+// GetSize returns the size of the popup.
+func (p DiscoveryPopup) GetSize() (width, height int) {
+	return p.width, p.height
+}
+
+// choose creates a message that confirms the choice of a candidate.
+func (p DiscoveryPopup) choose(index int) tea.Cmd {
+	candidate := p.candidates[index]
+	return func() tea.Msg {
+		return ChosenCandidateMsg{candidate.Name(p.name), candidate.URL, p.parent}
+	}
+}
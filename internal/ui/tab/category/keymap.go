@@ -4,9 +4,14 @@ import "github.com/charmbracelet/bubbles/key"
 
 // Keymap contains the key bindings for this tab
 type Keymap struct {
-	NewFeed    key.Binding
-	EditFeed   key.Binding
-	DeleteFeed key.Binding
+	NewFeed     key.Binding
+	NewCategory key.Binding
+	EditFeed    key.Binding
+	DeleteFeed  key.Binding
+	EditFilters key.Binding
+	MoveFeed    key.Binding
+	MoveUp      key.Binding
+	MoveDown    key.Binding
 }
 
 // DefaultKeymap contains the default key bindings for this tab
@@ -15,6 +20,10 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("n", "ctrl+n"),
 		key.WithHelp("n/ctrl+n", "New"),
 	),
+	NewCategory: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "New subcategory"),
+	),
 	EditFeed: key.NewBinding(
 		key.WithKeys("e", "ctrl+e"),
 		key.WithHelp("e/ctrl+e", "Edit"),
@@ -23,11 +32,32 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("d", "ctrl+d"),
 		key.WithHelp("d/ctrl+d", "Delete"),
 	),
+	EditFilters: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "Filters"),
+	),
+	MoveFeed: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "Move to another category"),
+	),
+	MoveUp: key.NewBinding(
+		key.WithKeys("<", "ctrl+up"),
+		key.WithHelp("</ctrl+↑", "Move item up"),
+	),
+	MoveDown: key.NewBinding(
+		key.WithKeys(">", "ctrl+down"),
+		key.WithHelp(">/ctrl+↓", "Move item down"),
+	),
 }
 
 // SetEnabled allows to disable/enable shortcuts
 func (m *Keymap) SetEnabled(enabled bool) {
 	m.NewFeed.SetEnabled(enabled)
+	m.NewCategory.SetEnabled(enabled)
 	m.EditFeed.SetEnabled(enabled)
 	m.DeleteFeed.SetEnabled(enabled)
+	m.EditFilters.SetEnabled(enabled)
+	m.MoveFeed.SetEnabled(enabled)
+	m.MoveUp.SetEnabled(enabled)
+	m.MoveDown.SetEnabled(enabled)
 }
@@ -0,0 +1,101 @@
+package category
+
+import (
+	"strings"
+
+	"github.com/TypicalAM/goread/internal/backend/rss"
+	"github.com/TypicalAM/goread/internal/theme"
+	"github.com/TypicalAM/goread/internal/ui/popup"
+	"github.com/TypicalAM/goread/internal/ui/simplelist"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ChosenDestinationMsg is the message sent when the category a feed is moved to is chosen.
+type ChosenDestinationMsg struct {
+	Name   string
+	Parent string
+	Target string
+}
+
+// MovePopup is the popup where a user chooses the category a feed is moved to.
+type MovePopup struct {
+	list    simplelist.Model
+	border  popup.TitleBorder
+	box     lipgloss.Style
+	targets []string
+	name    string
+	parent  string
+	width   int
+	height  int
+}
+
+// NewMovePopup returns a new move popup, the targets are the paths of the categories the feed can be moved to.
+func NewMovePopup(colors *theme.Colors, parent, name string, targets []string) MovePopup {
+	width := 60
+	height := 2*len(targets) + 6
+	if height > 22 {
+		height = 22
+	}
+
+	items := make([]list.Item, len(targets))
+	for i, target := range targets {
+		items[i] = simplelist.NewItem(strings.TrimSuffix(target, rss.PathSeparator), "")
+	}
+
+	categories := simplelist.New(colors, "Move "+name+" to", height-4, true)
+	categories.SetItems(items)
+
+	return MovePopup{
+		list:    categories,
+		border:  popup.NewTitleBorder("Move feed", width, height, colors.Color1, lipgloss.NormalBorder()),
+		box:     lipgloss.NewStyle().MaxWidth(width - 2),
+		targets: targets,
+		name:    name,
+		parent:  parent,
+		width:   width,
+		height:  height,
+	}
+}
+
+// Init initializes the popup.
+func (p MovePopup) Init() tea.Cmd {
+	return nil
+}
+
+// Update updates the popup.
+func (p MovePopup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if key.Matches(msg, p.list.Keymap.Open) && !p.list.IsEmpty() {
+			return p, p.choose(p.list.Index())
+		}
+
+		if _, ok := p.list.QuickSelect(msg); ok {
+			return p, p.choose(p.list.Index())
+		}
+	}
+
+	var cmd tea.Cmd
+	p.list, cmd = p.list.Update(msg)
+	return p, cmd
+}
+
+// View renders the popup.
+func (p MovePopup) View() string {
+	return p.border.Render(p.box.Render(p.list.View()))
+}
+
+// GetSize returns the size of the popup.
+func (p MovePopup) GetSize() (width, height int) {
+	return p.width, p.height
+}
+
+// choose creates a message that confirms the choice of a category.
+func (p MovePopup) choose(index int) tea.Cmd {
+	target := p.targets[index]
+	return func() tea.Msg {
+		return ChosenDestinationMsg{p.name, p.parent, target}
+	}
+}
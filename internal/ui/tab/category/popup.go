@@ -64,6 +64,8 @@ func NewPopup(colors *theme.Colors, oldName, oldURL, parent string) Popup {
 	if editing {
 		nameInput.SetValue(oldName)
 		urlInput.SetValue(oldURL)
+	} else {
+		nameInput.Placeholder = "from the feed"
 	}
 
 	nameInput.Focus()
@@ -0,0 +1,138 @@
+package category
+
+import (
+	"github.com/TypicalAM/goread/internal/theme"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ChosenCategoryMsg is the message displayed when a subcategory is successfully chosen.
+type ChosenCategoryMsg struct {
+	Name    string
+	Desc    string
+	OldName string
+	Parent  string
+	IsEdit  bool
+}
+
+// CategoryPopup is the subcategory popup where a user can create/edit a subcategory.
+type CategoryPopup struct {
+	nameInput textinput.Model
+	descInput textinput.Model
+	style     popupStyle
+	oldName   string
+	parent    string
+	editing   bool
+	width     int
+	height    int
+}
+
+// NewCategoryPopup returns a new subcategory popup.
+func NewCategoryPopup(colors *theme.Colors, oldName, oldDesc, parent string) CategoryPopup {
+	width := 46
+	height := 7
+
+	editing := oldName != ""
+
+	nameInput := textinput.New()
+	nameInput.CharLimit = 30
+	nameInput.Prompt = "Name: "
+	nameInput.Width = width - 20
+	descInput := textinput.New()
+	descInput.CharLimit = 30
+	descInput.Prompt = "Description: "
+	descInput.Width = width - 27
+
+	var style popupStyle
+	if editing {
+		style = newPopupStyle(colors, width, height, "Edit subcategory")
+		nameInput.SetValue(oldName)
+		descInput.SetValue(oldDesc)
+	} else {
+		style = newPopupStyle(colors, width, height, "New subcategory")
+	}
+
+	nameInput.Focus()
+
+	return CategoryPopup{
+		style:     style,
+		nameInput: nameInput,
+		descInput: descInput,
+		oldName:   oldName,
+		parent:    parent,
+		editing:   editing,
+		width:     width,
+		height:    height,
+	}
+}
+
+// Init initializes the popup.
+func (p CategoryPopup) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update updates the popup.
+func (p CategoryPopup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "down", "up", "tab":
+			if p.nameInput.Focused() {
+				p.nameInput.Blur()
+				cmds = append(cmds, p.descInput.Focus())
+			} else {
+				p.descInput.Blur()
+				cmds = append(cmds, p.nameInput.Focus())
+			}
+
+		case "enter":
+			return p, confirmCategory(
+				p.nameInput.Value(),
+				p.descInput.Value(),
+				p.oldName,
+				p.parent,
+				p.editing,
+			)
+		}
+	}
+
+	if p.nameInput.Focused() {
+		var cmd tea.Cmd
+		p.nameInput, cmd = p.nameInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	if p.descInput.Focused() {
+		var cmd tea.Cmd
+		p.descInput, cmd = p.descInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return p, tea.Batch(cmds...)
+}
+
+// View renders the popup.
+func (p CategoryPopup) View() string {
+	itemText := "New subcategory"
+	if p.editing {
+		itemText = "Your subcategory"
+	}
+
+	itemTitle := p.style.itemTitle.Render(itemText)
+	name := p.style.itemField.Render(p.nameInput.View())
+	desc := p.style.itemField.Render(p.descInput.View())
+	listItem := p.style.listItem.Render(lipgloss.JoinVertical(lipgloss.Left, itemTitle, name, desc))
+	return p.style.border.Render(listItem)
+}
+
+// GetSize returns the size of the popup.
+func (p CategoryPopup) GetSize() (width, height int) {
+	return p.width, p.height
+}
+
+// confirmCategory creates a message that confirms the user's choice.
+func confirmCategory(name, desc, oldName, parent string, edit bool) tea.Cmd {
+	return func() tea.Msg { return ChosenCategoryMsg{name, desc, oldName, parent, edit} }
+}
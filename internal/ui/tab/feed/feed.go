@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/TypicalAM/goread/internal/backend"
+	"github.com/TypicalAM/goread/internal/backend/cache"
 	"github.com/TypicalAM/goread/internal/theme"
 	"github.com/TypicalAM/goread/internal/ui/popup/lollypops"
 	"github.com/TypicalAM/goread/internal/ui/tab"
@@ -41,6 +42,10 @@ type Model struct {
 	viewportOpen    bool
 	viewportFocused bool
 	lastFilterState list.FilterState
+	saveable        bool
+	deletable       bool
+	exportable      bool
+	annotatable     bool
 }
 
 // New creates a new feed tab with sensible defaults
@@ -61,6 +66,11 @@ func New(colors *theme.Colors, width, height int, title string, fetcher backend.
 		title:    title,
 		fetcher:  fetcher,
 		keymap:   DefaultKeymap,
+
+		saveable:    true,
+		deletable:   true,
+		exportable:  true,
+		annotatable: true,
 	}
 }
 
@@ -123,10 +133,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case backend.FetchArticleSuccessMsg:
-		return m.loadTab(msg.Items), nil
+		return m.loadTab(msg.Items), backend.ExtractNewItems(m.title)
+
+	case backend.ArticlesReloadedMsg:
+		if msg.FeedName != m.title || !m.loaded {
+			return m, nil
+		}
+
+		return m.reloadItems(msg.Items)
+
+	case backend.ArticleExtractedMsg:
+		if !m.loaded {
+			return m, nil
+		}
+
+		return m.setContent(msg.URL, msg.MarkdownContent)
 
 	case backend.SetEnableKeybindMsg:
-		m.keymap.SetEnabled(bool(msg))
+		m.setEnabled(bool(msg))
 		return m, nil
 
 	case lollypops.ChoiceResultMsg:
@@ -255,7 +279,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			index := absListIndex(&m.list, selectedItem.FilterValue())
 			selectedItem.ArtTitle = strings.Join(strings.Split(selectedItem.ArtTitle, " ")[1:], " ")
 			cmd := m.list.SetItem(index, selectedItem)
-			return m, tea.Batch(cmd, backend.MarkAsUnread(selectedItem.FeedURL))
+			return m, tea.Batch(cmd, backend.MarkAsUnread(selectedItem.ID))
+
+		case key.Matches(msg, m.keymap.ExportSaved):
+			return m, backend.ExportSaved()
+
+		case key.Matches(msg, m.keymap.Annotate):
+			if m.list.SelectedItem() == nil {
+				return m, nil
+			}
+
+			selectedItem := m.list.SelectedItem().(backend.ArticleItem)
+			return m, backend.AnnotateItem(selectedItem.ID, selectedItem.Tags, selectedItem.Note)
+
+		case key.Matches(msg, m.keymap.FullContent):
+			if item := m.list.SelectedItem(); item != nil {
+				return m, backend.ExtractItem(m.title, absListIndex(&m.list, item.FilterValue()))
+			}
+
+			return m, nil
 
 		case key.Matches(msg, m.keymap.CycleSelection):
 			if !m.viewportFocused {
@@ -310,6 +352,7 @@ func (m Model) loadTab(items []list.Item) tab.Tab {
 
 	m.list = list.New(items, itemDelegate, m.style.listWidth, m.height)
 
+	m.list.Filter = filterByTag
 	m.list.SetShowHelp(false)
 	m.list.SetShowTitle(false)
 	m.list.SetShowStatusBar(false)
@@ -349,6 +392,53 @@ func (m Model) loadTab(items []list.Item) tab.Tab {
 	return m
 }
 
+// reloadItems replaces the items of an already loaded tab, keeping the cursor on the same
+// article. The viewport is left alone so that the article being read doesn't jump around.
+func (m Model) reloadItems(items []list.Item) (tab.Tab, tea.Cmd) {
+	for i := range items {
+		item := items[i].(backend.ArticleItem)
+		item.Desc = wrap.String(item.RawDesc, m.style.listWidth-4)
+		items[i] = item
+	}
+
+	var selectedURL string
+	if selected, ok := m.list.SelectedItem().(backend.ArticleItem); ok {
+		selectedURL = selected.FeedURL
+	}
+
+	cmd := m.list.SetItems(items)
+	for i, item := range m.list.VisibleItems() {
+		if item.(backend.ArticleItem).FeedURL == selectedURL {
+			m.list.Select(i)
+			break
+		}
+	}
+
+	return m, cmd
+}
+
+// setContent replaces the content of the article with the given url, the viewport is
+// updated if the article is currently being viewed
+func (m Model) setContent(url, markdown string) (tab.Tab, tea.Cmd) {
+	var cmds []tea.Cmd
+	for i, listItem := range m.list.Items() {
+		item := listItem.(backend.ArticleItem)
+		if item.FeedURL != url {
+			continue
+		}
+
+		item.MarkdownContent = markdown
+		cmds = append(cmds, m.list.SetItem(i, item))
+	}
+
+	if selected, ok := m.list.SelectedItem().(backend.ArticleItem); ok && selected.FeedURL == url {
+		updated, cmd := m.updateViewport()
+		return updated, tea.Batch(append(cmds, cmd)...)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
 // nolint:unparam
 // updateViewport displays the viewport content
 func (m Model) updateViewport() (tab.Tab, tea.Cmd) {
@@ -390,7 +480,7 @@ func (m Model) markAsRead() (tab.Tab, tea.Cmd) {
 	index := absListIndex(&m.list, selectedItem.FilterValue())
 	selectedItem.ArtTitle = "✓ " + selectedItem.ArtTitle
 	cmd := m.list.SetItem(index, selectedItem)
-	return m, tea.Batch(cmd, backend.MarkAsRead(selectedItem.FeedURL))
+	return m, tea.Batch(cmd, backend.MarkAsRead(selectedItem.ID))
 }
 
 // markAsSaved sets the selected article as saved.
@@ -439,22 +529,47 @@ func (m Model) View() string {
 
 // DisableSaving disables the saving of the article
 func (m Model) DisableSaving() Model {
+	m.saveable = false
 	m.keymap.SaveArticle.SetEnabled(false)
 	return m
 }
 
 // DisableDeleting disables the deleting of the article
 func (m Model) DisableDeleting() Model {
+	m.deletable = false
 	m.keymap.DeleteFromSaved.SetEnabled(false)
 	return m
 }
 
+// DisableExporting disables the exporting of the saved articles
+func (m Model) DisableExporting() Model {
+	m.exportable = false
+	m.keymap.ExportSaved.SetEnabled(false)
+	return m
+}
+
+// DisableAnnotating disables the editing of the tags and the notes of the articles
+func (m Model) DisableAnnotating() Model {
+	m.annotatable = false
+	m.keymap.Annotate.SetEnabled(false)
+	return m
+}
+
+// setEnabled enables or disables the keymap, the shortcuts disabled for this tab stay disabled
+func (m *Model) setEnabled(enabled bool) {
+	m.keymap.SetEnabled(enabled)
+	m.keymap.SaveArticle.SetEnabled(enabled && m.saveable)
+	m.keymap.DeleteFromSaved.SetEnabled(enabled && m.deletable)
+	m.keymap.ExportSaved.SetEnabled(enabled && m.exportable)
+	m.keymap.Annotate.SetEnabled(enabled && m.annotatable)
+}
+
 // ShortHelp returns the short help for the tab
 func (m Model) ShortHelp() []key.Binding {
 	return []key.Binding{
 		m.keymap.Open, m.keymap.ToggleFocus, m.keymap.RefreshArticles, m.keymap.OpenInPager,
 		m.keymap.SaveArticle, m.keymap.DeleteFromSaved, m.keymap.CycleSelection,
-		m.keymap.MarkAsUnread,
+		m.keymap.MarkAsUnread, m.keymap.FullContent, m.keymap.ExportSaved, m.keymap.Annotate,
 	}
 }
 
@@ -503,6 +618,23 @@ func (m Model) showLoading() string {
 	)
 }
 
+// filterByTag filters the articles by their tags when the term starts with a hash, the tags of an
+// article are a part of its filter value. Any other term is matched using the default fuzzy filter.
+func filterByTag(term string, targets []string) []list.Rank {
+	if !strings.HasPrefix(term, "#") || len(term) == 1 {
+		return list.DefaultFilter(term, targets)
+	}
+
+	var ranks []list.Rank
+	for i, target := range targets {
+		if cache.HasTag(backend.FilterValueTags(target), term) {
+			ranks = append(ranks, list.Rank{Index: i})
+		}
+	}
+
+	return ranks
+}
+
 // absListIndex returns the absolute index of the currently selected item.
 func absListIndex(l *list.Model, target string) int {
 	if l.FilterState() == list.Unfiltered {
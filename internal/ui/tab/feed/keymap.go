@@ -12,6 +12,9 @@ type Keymap struct {
 	DeleteFromSaved key.Binding
 	CycleSelection  key.Binding
 	MarkAsUnread    key.Binding
+	FullContent     key.Binding
+	ExportSaved     key.Binding
+	Annotate        key.Binding
 }
 
 // DefaultKeymap contains the default key bindings for this tab
@@ -48,6 +51,18 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("u"),
 		key.WithHelp("u", "Mark as unread"),
 	),
+	FullContent: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "Full article"),
+	),
+	ExportSaved: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "Export saved"),
+	),
+	Annotate: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "Tags & note"),
+	),
 }
 
 // SetEnabled allows to disable/enable shortcuts
@@ -59,4 +74,7 @@ func (m *Keymap) SetEnabled(enabled bool) {
 	m.DeleteFromSaved.SetEnabled(enabled)
 	m.CycleSelection.SetEnabled(enabled)
 	m.MarkAsUnread.SetEnabled(enabled)
+	m.FullContent.SetEnabled(enabled)
+	m.ExportSaved.SetEnabled(enabled)
+	m.Annotate.SetEnabled(enabled)
 }
@@ -0,0 +1,127 @@
+package feed
+
+import (
+	"github.com/TypicalAM/goread/internal/backend/cache"
+	"github.com/TypicalAM/goread/internal/theme"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ChosenAnnotationMsg is the message sent when the tags and the note of a saved article are confirmed.
+type ChosenAnnotationMsg struct {
+	ID   cache.ArticleID
+	Tags string
+	Note string
+}
+
+// focusedField is the field that is currently focused.
+type focusedField int
+
+const (
+	tagsField focusedField = iota
+	noteField
+)
+
+// Popup is the popup where a user can edit the tags and the note of a saved article.
+type Popup struct {
+	tagsInput textinput.Model
+	noteInput textinput.Model
+	style     popupStyle
+	id        cache.ArticleID
+	focused   focusedField
+	width     int
+	height    int
+}
+
+// NewPopup returns a new annotation popup.
+func NewPopup(colors *theme.Colors, id cache.ArticleID, oldTags, oldNote string) Popup {
+	width := 60
+	height := 7
+
+	tagsInput := textinput.New()
+	tagsInput.CharLimit = 100
+	tagsInput.Prompt = "Tags: "
+	tagsInput.Placeholder = "comma separated"
+	tagsInput.Width = width - 20
+	tagsInput.SetValue(oldTags)
+	noteInput := textinput.New()
+	noteInput.CharLimit = 280
+	noteInput.Prompt = "Note: "
+	noteInput.Width = width - 20
+	noteInput.SetValue(oldNote)
+
+	tagsInput.Focus()
+
+	return Popup{
+		style:     newPopupStyle(colors, width, height, "Tags & note"),
+		tagsInput: tagsInput,
+		noteInput: noteInput,
+		id:        id,
+		width:     width,
+		height:    height,
+	}
+}
+
+// Init initializes the popup.
+func (p Popup) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update updates the popup.
+func (p Popup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "down", "up", "tab":
+			switch p.focused {
+			case tagsField:
+				p.focused = noteField
+				p.tagsInput.Blur()
+				cmds = append(cmds, p.noteInput.Focus())
+
+			case noteField:
+				p.focused = tagsField
+				p.noteInput.Blur()
+				cmds = append(cmds, p.tagsInput.Focus())
+			}
+
+		case "enter":
+			return p, confirm(p.id, p.tagsInput.Value(), p.noteInput.Value())
+		}
+	}
+
+	if p.tagsInput.Focused() {
+		var cmd tea.Cmd
+		p.tagsInput, cmd = p.tagsInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	if p.noteInput.Focused() {
+		var cmd tea.Cmd
+		p.noteInput, cmd = p.noteInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return p, tea.Batch(cmds...)
+}
+
+// View renders the popup.
+func (p Popup) View() string {
+	itemTitle := p.style.itemTitle.Render("Saved article")
+	tags := p.style.itemField.Render(p.tagsInput.View())
+	note := p.style.itemField.Render(p.noteInput.View())
+	listItem := p.style.listItem.Render(lipgloss.JoinVertical(lipgloss.Left, itemTitle, tags, note))
+	return p.style.border.Render(listItem)
+}
+
+// GetSize returns the size of the popup.
+func (p Popup) GetSize() (width, height int) {
+	return p.width, p.height
+}
+
+// confirm creates a message that confirms the user's choice.
+func confirm(id cache.ArticleID, tags, note string) tea.Cmd {
+	return func() tea.Msg { return ChosenAnnotationMsg{id, tags, note} }
+}
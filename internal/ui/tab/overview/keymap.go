@@ -7,6 +7,9 @@ type Keymap struct {
 	NewCategory    key.Binding
 	EditCategory   key.Binding
 	DeleteCategory key.Binding
+	EditFilters    key.Binding
+	MoveUp         key.Binding
+	MoveDown       key.Binding
 }
 
 // DefaultKeymap contains the default key bindings for this tab
@@ -23,6 +26,18 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("d", "ctrl+d"),
 		key.WithHelp("d/ctrl+d", "Delete"),
 	),
+	EditFilters: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "Filters"),
+	),
+	MoveUp: key.NewBinding(
+		key.WithKeys("<", "ctrl+up"),
+		key.WithHelp("</ctrl+↑", "Move item up"),
+	),
+	MoveDown: key.NewBinding(
+		key.WithKeys(">", "ctrl+down"),
+		key.WithHelp(">/ctrl+↓", "Move item down"),
+	),
 }
 
 // SetEnabled allows to disable/enable shortcuts
@@ -30,4 +45,7 @@ func (m *Keymap) SetEnabled(enabled bool) {
 	m.NewCategory.SetEnabled(enabled)
 	m.EditCategory.SetEnabled(enabled)
 	m.DeleteCategory.SetEnabled(enabled)
+	m.EditFilters.SetEnabled(enabled)
+	m.MoveUp.SetEnabled(enabled)
+	m.MoveDown.SetEnabled(enabled)
 }
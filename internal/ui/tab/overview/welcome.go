@@ -111,6 +111,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if item, ok := m.list.QuickSelect(msg); ok {
+			return m, tab.NewTab(m, item.FilterValue())
+		}
+
 		switch {
 		case msg.String() == "esc":
 			return m, backend.StartQuitting()
@@ -137,11 +141,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, backend.MakeChoice("Delete category?", true)
 			}
 
-		default:
-			// Check if we need to open a new category
-			if item, ok := m.list.GetItem(msg.String()); ok {
-				return m, tab.NewTab(m, item.FilterValue())
+		case key.Matches(msg, m.keymap.EditFilters):
+			if !m.list.IsEmpty() {
+				return m, backend.EditFilters(m, m.list.SelectedItem().FilterValue())
 			}
+
+		case key.Matches(msg, m.keymap.MoveUp):
+			return m.reorder(-1)
+
+		case key.Matches(msg, m.keymap.MoveDown):
+			return m.reorder(1)
 		}
 	}
 
@@ -150,6 +159,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// reorder moves the selected category by the offset, the selection follows it
+func (m Model) reorder(offset int) (tea.Model, tea.Cmd) {
+	if m.list.IsEmpty() {
+		return m, nil
+	}
+
+	name := m.list.SelectedItem().FilterValue()
+	m.list.SetIndex(m.list.Index() + offset)
+	return m, backend.ReorderItem(m, name, offset)
+}
+
 // View returns the view for the tab
 func (m Model) View() string {
 	if !m.loaded {
@@ -161,7 +181,10 @@ func (m Model) View() string {
 
 // ShortHelp returns the short help for this tab
 func (m Model) ShortHelp() []key.Binding {
-	return []key.Binding{m.keymap.NewCategory, m.keymap.EditCategory, m.keymap.DeleteCategory}
+	return []key.Binding{
+		m.keymap.NewCategory, m.keymap.EditCategory, m.keymap.DeleteCategory,
+		m.keymap.EditFilters, m.keymap.MoveUp, m.keymap.MoveDown,
+	}
 }
 
 // FullHelp returns the full help for this tab